@@ -1,18 +1,34 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+	// Speed profile timezones resolve without the host's zoneinfo
+	_ "time/tzdata"
 
 	"github.com/SHIVAMSINGH0101/go-demo/internal/config"
 	"github.com/SHIVAMSINGH0101/go-demo/internal/database"
+	"github.com/SHIVAMSINGH0101/go-demo/internal/events"
 	"github.com/SHIVAMSINGH0101/go-demo/internal/handlers"
 	"github.com/SHIVAMSINGH0101/go-demo/internal/repository"
 	"github.com/SHIVAMSINGH0101/go-demo/internal/services"
+	"github.com/SHIVAMSINGH0101/go-demo/internal/utils"
 	"github.com/gorilla/mux"
 )
 
 func main() {
+	// Cancelled on SIGINT or SIGTERM, stops the background workers and the server
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -26,26 +42,156 @@ func main() {
 	}
 	defer db.Close()
 
+	// Bring the schema up to date before serving, off by default so deploys migrate on their own
+	if cfg.Database.AutoMigrate {
+		migrator, err := database.NewMigrator(db, cfg.Database.Driver)
+		if err != nil {
+			log.Fatal("Failed to load migrations:", err)
+		}
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
+		for _, migration := range applied {
+			log.Printf("Applied migration %04d_%s", migration.Version, migration.Name)
+		}
+	}
+
 	router := mux.NewRouter()
 
 	api := router.PathPrefix("/api/v1").Subrouter()
 
-	// Initialize repository layer
-	routeRepo := repository.NewOrderRepository(db)
+	// Travel time estimator used for route computation
+	estimator, err := utils.NewTravelTimeEstimator(cfg.Routing)
+	if err != nil {
+		log.Fatal("Failed to initialize travel time estimator:", err)
+	}
+
+	if _, err := utils.ValidateObjective(cfg.Routing.Objective); err != nil {
+		log.Fatal("Invalid route objective:", err)
+	}
+
+	// Vehicle speed by time of day
+	speedProfiles, err := utils.LoadSpeedProfiles(cfg.Routing.SpeedProfilesPath, cfg.Routing.Timezone)
+	if err != nil {
+		log.Fatal("Failed to load speed profiles:", err)
+	}
+
+	// Initialize repository layer for the configured driver, every call is cancelled after its deadline
+	deadlines := repository.Deadlines{
+		Query: time.Duration(cfg.Database.QueryTimeoutMs) * time.Millisecond,
+		Tx:    time.Duration(cfg.Database.TxTimeoutMs) * time.Millisecond,
+	}
+	repos, unitOfWork, err := repository.NewRepositories(db, cfg.Database.Driver, deadlines)
+	if err != nil {
+		log.Fatal("Failed to initialize repositories:", err)
+	}
+	orderRepo := repos.Orders
+	riderRepo := repos.Riders
+	routePlanRepo := repos.RoutePlans
+	restaurantRepo := repos.Restaurants
+	customerRepo := repos.Customers
 
 	// Initialize service layer
-	routeService := services.NewOrderService(routeRepo)
+	orderService := services.NewOrderService(orderRepo, restaurantRepo, customerRepo, unitOfWork)
+	riderService := services.NewRiderService(riderRepo, orderRepo, unitOfWork, speedProfiles)
+	routeService := services.NewRouteService(orderRepo, riderRepo, cfg.Routing, estimator, speedProfiles)
+
+	// Events published by services, streamed to clients over SSE
+	broker := events.NewBroker()
+
+	dispatchService, err := services.NewDispatchService(orderRepo, riderRepo, routeService, unitOfWork, broker, cfg.Dispatch)
+	if err != nil {
+		log.Fatal("Failed to initialize dispatcher:", err)
+	}
+	replanService := services.NewReplanService(orderRepo, riderRepo, routePlanRepo, routeService, broker,
+		estimator, speedProfiles, cfg.Replan)
+
+	// Background workers, waited for on shutdown so none is mid-write when the database closes
+	var workers sync.WaitGroup
+	if cfg.Dispatch.Enabled {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			dispatchService.Run(ctx)
+		}()
+	}
+	if cfg.Replan.Enabled {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			replanService.Run(ctx)
+		}()
+	}
 
 	// Initialize handlers
-	orderHandler := handlers.NewOrderHandler(routeService)
+	orderHandler := handlers.NewOrderHandler(orderService, routeService)
 	orderHandler.RegisterOrderHandlers(api)
 
+	planHandler := handlers.NewPlanHandler(routeService)
+	planHandler.RegisterPlanHandlers(api)
+
+	locationHandler := handlers.NewLocationHandler(orderService)
+	locationHandler.RegisterLocationHandlers(api)
+
+	hubHandler := handlers.NewHubHandler(orderService)
+	hubHandler.RegisterHubHandlers(api)
+
+	restaurantHandler := handlers.NewRestaurantHandler(orderService)
+	restaurantHandler.RegisterRestaurantHandlers(api)
+
+	customerHandler := handlers.NewCustomerHandler(orderService)
+	customerHandler.RegisterCustomerHandlers(api)
+
+	riderHandler := handlers.NewRiderHandler(riderService, replanService)
+	riderHandler.RegisterRiderHandlers(api)
+
+	dispatchHandler := handlers.NewDispatchHandler(dispatchService)
+	dispatchHandler.RegisterDispatchHandlers(api)
+
+	eventHandler := handlers.NewEventHandler(broker)
+	eventHandler.RegisterEventHandlers(api)
+
 	// Health check
 	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	}).Methods("GET")
 
+	server := &http.Server{
+		Addr:    ":" + cfg.Server.Port,
+		Handler: router,
+		// Requests see the shutdown too, so open event streams end instead of holding Shutdown up
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	// ListenAndServe returns as soon as Shutdown starts, main waits for in-flight requests and workers here
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		<-ctx.Done()
+		log.Printf("Server shutting down")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Server shutdown failed, err %+v", err)
+		}
+
+		stopped := make(chan struct{})
+		go func() {
+			workers.Wait()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-shutdownCtx.Done():
+			log.Printf("Background workers still running after shutdown timeout")
+		}
+	}()
+
 	log.Printf("Server starting on port %s", cfg.Server.Port)
-	log.Fatal(http.ListenAndServe(":"+cfg.Server.Port, router))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal("Server failed:", err)
+	}
+	<-drained
 }
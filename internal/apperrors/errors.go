@@ -0,0 +1,43 @@
+// Package apperrors holds the kinds of domain errors the layers agree on.
+// Repositories and services wrap them, handlers turn them into HTTP statuses.
+package apperrors
+
+import "errors"
+
+var (
+	// ErrNotFound - The entity asked for does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict - The request clashes with the stored state, retrying as is will not help
+	ErrConflict = errors.New("conflict")
+	// ErrValidation - The request is well formed but can not be carried out
+	ErrValidation = errors.New("validation failed")
+)
+
+// kindError - An error of a kind with its own message, and optionally the error that caused it
+type kindError struct {
+	kind  error
+	msg   string
+	cause error
+}
+
+// Error - Only the message, a wrapped cause is not meant for clients
+func (e *kindError) Error() string {
+	return e.msg
+}
+
+func (e *kindError) Unwrap() []error {
+	if e.cause == nil {
+		return []error{e.kind}
+	}
+	return []error{e.kind, e.cause}
+}
+
+// New - An error of kind with msg as its whole message, so sentinels read as before
+func New(kind error, msg string) error {
+	return &kindError{kind: kind, msg: msg}
+}
+
+// Wrap - An error of kind that keeps cause in its chain, e.g. a driver error
+func Wrap(kind error, msg string, cause error) error {
+	return &kindError{kind: kind, msg: msg, cause: cause}
+}
@@ -9,6 +9,9 @@ import (
 type Config struct {
 	Server   ServerConfig
 	Database DatabaseConfig
+	Routing  RoutingConfig
+	Dispatch DispatchConfig
+	Replan   ReplanConfig
 }
 
 // ServerConfig holds server configuration
@@ -18,11 +21,65 @@ type ServerConfig struct {
 
 // DatabaseConfig holds database configuration
 type DatabaseConfig struct {
+	// Driver is "mysql" or "sqlite", SQLite keeps everything in the SQLitePath file
+	Driver     string
+	SQLitePath string
+
 	Host     string
 	Port     int
 	User     string
 	Password string
 	DBName   string
+	// QueryTimeoutMs bounds a single query, TxTimeoutMs a whole transaction, 0 for no limit
+	QueryTimeoutMs int
+	TxTimeoutMs    int
+	// AutoMigrate applies pending migrations when the server starts
+	AutoMigrate bool
+}
+
+// RoutingConfig holds best route computation limits
+type RoutingConfig struct {
+	MaxOrders      int
+	ExactMaxOrders int
+	// TravelEstimator is "haversine" or "road_graph"
+	TravelEstimator string
+	// RoadGraphNodesPath and RoadGraphEdgesPath are CSV files, an OSM PBF extract has to be converted to them first
+	RoadGraphNodesPath string
+	RoadGraphEdgesPath string
+	// SpeedProfilesPath is a JSON file of vehicle speed profiles, built-in profiles are used when empty
+	SpeedProfilesPath string
+	// Timezone is the IANA zone the speed profiles' hours are in
+	Timezone string
+	// Objective is the default route objective: "makespan", "sum_delivery_time" or "weighted_lateness"
+	Objective string
+	// FleetMaxOrders and FleetMaxRiders limit a multi-rider plan
+	FleetMaxOrders int
+	FleetMaxRiders int
+}
+
+// DispatchConfig holds order to rider matching settings
+type DispatchConfig struct {
+	Enabled         bool
+	IntervalSeconds int
+	// Method is "greedy" (bundles orders) or "hungarian" (one order per rider per tick)
+	Method          string
+	MaxBundleSize   int
+	CandidateRiders int
+	MaxExtraMinutes int
+	BatchSize       int
+}
+
+// ReplanConfig holds when a rider's plan is recomputed from their GPS pings
+type ReplanConfig struct {
+	Enabled bool
+	// MaxOffRouteKm and MaxDelayMinutes are the thresholds that trigger a re-plan
+	MaxOffRouteKm   float64
+	MaxDelayMinutes float64
+	// MinIntervalSeconds keeps a rider from being re-planned on every ping
+	MinIntervalSeconds int
+	// Workers check pings off the request path, QueueSize riders can wait for them
+	Workers   int
+	QueueSize int
 }
 
 func Load() (*Config, error) {
@@ -31,11 +88,47 @@ func Load() (*Config, error) {
 			Port: getEnv("SERVER_PORT", "8080"),
 		},
 		Database: DatabaseConfig{
+			Driver:     getEnv("DB_DRIVER", "mysql"),
+			SQLitePath: getEnv("DB_SQLITE_PATH", "ordersdb.sqlite"),
+
 			Host:     getEnv("DB_HOST", "localhost"),
 			Port:     getEnvAsInt("DB_PORT", 3306),
 			User:     getEnv("DB_USER", "root"),
 			Password: getEnv("DB_PASSWORD", "wifiname"),
 			DBName:   getEnv("DB_NAME", "ordersdb"),
+
+			QueryTimeoutMs: getEnvAsInt("DB_QUERY_TIMEOUT_MS", 3000),
+			TxTimeoutMs:    getEnvAsInt("DB_TX_TIMEOUT_MS", 10000),
+			AutoMigrate:    getEnvAsBool("DB_AUTO_MIGRATE", false),
+		},
+		Routing: RoutingConfig{
+			MaxOrders:          getEnvAsInt("ROUTE_MAX_ORDERS", 20),
+			ExactMaxOrders:     getEnvAsInt("ROUTE_EXACT_MAX_ORDERS", 6),
+			TravelEstimator:    getEnv("ROUTE_TRAVEL_ESTIMATOR", "haversine"),
+			RoadGraphNodesPath: getEnv("ROAD_GRAPH_NODES", "data/road_nodes.csv"),
+			RoadGraphEdgesPath: getEnv("ROAD_GRAPH_EDGES", "data/road_edges.csv"),
+			SpeedProfilesPath:  getEnv("ROUTE_SPEED_PROFILES", ""),
+			Timezone:           getEnv("ROUTE_TIMEZONE", "Asia/Kolkata"),
+			Objective:          getEnv("ROUTE_OBJECTIVE", "makespan"),
+			FleetMaxOrders:     getEnvAsInt("ROUTE_FLEET_MAX_ORDERS", 30),
+			FleetMaxRiders:     getEnvAsInt("ROUTE_FLEET_MAX_RIDERS", 20),
+		},
+		Dispatch: DispatchConfig{
+			Enabled:         getEnvAsBool("DISPATCH_ENABLED", false),
+			IntervalSeconds: getEnvAsInt("DISPATCH_INTERVAL_SECONDS", 30),
+			Method:          getEnv("DISPATCH_METHOD", "greedy"),
+			MaxBundleSize:   getEnvAsInt("DISPATCH_MAX_BUNDLE_SIZE", 2),
+			CandidateRiders: getEnvAsInt("DISPATCH_CANDIDATE_RIDERS", 5),
+			MaxExtraMinutes: getEnvAsInt("DISPATCH_MAX_EXTRA_MINUTES", 45),
+			BatchSize:       getEnvAsInt("DISPATCH_BATCH_SIZE", 100),
+		},
+		Replan: ReplanConfig{
+			Enabled:            getEnvAsBool("REPLAN_ENABLED", true),
+			MaxOffRouteKm:      getEnvAsFloat("REPLAN_MAX_OFF_ROUTE_KM", 1),
+			MaxDelayMinutes:    getEnvAsFloat("REPLAN_MAX_DELAY_MINUTES", 10),
+			MinIntervalSeconds: getEnvAsInt("REPLAN_MIN_INTERVAL_SECONDS", 60),
+			Workers:            getEnvAsInt("REPLAN_WORKERS", 4),
+			QueueSize:          getEnvAsInt("REPLAN_QUEUE_SIZE", 1000),
 		},
 	}
 
@@ -56,3 +149,19 @@ func getEnvAsInt(name string, defaultVal int) int {
 	}
 	return defaultVal
 }
+
+func getEnvAsFloat(name string, defaultVal float64) float64 {
+	valueStr := getEnv(name, "")
+	if value, err := strconv.ParseFloat(valueStr, 64); err == nil {
+		return value
+	}
+	return defaultVal
+}
+
+func getEnvAsBool(name string, defaultVal bool) bool {
+	valueStr := getEnv(name, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultVal
+}
@@ -3,14 +3,33 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"net/url"
 
 	"github.com/SHIVAMSINGH0101/go-demo/internal/config"
 	_ "github.com/go-sql-driver/mysql"
+	_ "modernc.org/sqlite"
+)
+
+// Database drivers DB_DRIVER can name
+const (
+	DriverMySQL  = "mysql"
+	DriverSQLite = "sqlite"
 )
 
 // NewConnection creates a new database connection
 func NewConnection(cfg config.DatabaseConfig) (*sql.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+	switch cfg.Driver {
+	case DriverMySQL:
+		return newMySQLConnection(cfg)
+	case DriverSQLite:
+		return newSQLiteConnection(cfg)
+	default:
+		return nil, fmt.Errorf("unknown database driver %q, expected %s or %s", cfg.Driver, DriverMySQL, DriverSQLite)
+	}
+}
+
+func newMySQLConnection(cfg config.DatabaseConfig) (*sql.DB, error) {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local&clientFoundRows=true",
 		cfg.User,
 		cfg.Password,
 		cfg.Host,
@@ -18,7 +37,7 @@ func NewConnection(cfg config.DatabaseConfig) (*sql.DB, error) {
 		cfg.DBName,
 	)
 
-	db, err := sql.Open("mysql", dsn)
+	db, err := sql.Open(DriverMySQL, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -32,3 +51,33 @@ func NewConnection(cfg config.DatabaseConfig) (*sql.DB, error) {
 
 	return db, nil
 }
+
+/*
+* newSQLiteConnection - Opens the SQLite file at cfg.SQLitePath, ":memory:"
+* for a database that lives as long as the process. Foreign keys are
+* enforced like on MySQL and times are stored as unix milliseconds, so
+* they compare correctly whatever zone they were written in.
+ */
+func newSQLiteConnection(cfg config.DatabaseConfig) (*sql.DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Set("_time_integer_format", "unix_milli")
+	params.Set("_inttotime", "1")
+
+	db, err := sql.Open(DriverSQLite, "file:"+cfg.SQLitePath+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	// SQLite has one writer at a time, and an in-memory database is private to its connection
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+
+	return db, nil
+}
@@ -0,0 +1,324 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/config"
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0002_add_index.up.sql":      {Data: []byte("CREATE INDEX a ON b (c);")},
+		"m/0002_add_index.down.sql":    {Data: []byte("DROP INDEX a ON b;")},
+		"m/0001_create_table.up.sql":   {Data: []byte("CREATE TABLE b (c INT);")},
+		"m/0001_create_table.down.sql": {Data: []byte("DROP TABLE b;")},
+	}
+
+	migrations, err := loadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("loaded %d migrations, want 2", len(migrations))
+	}
+	if migrations[0].Version != 1 || migrations[0].Name != "create_table" || migrations[1].Version != 2 {
+		t.Errorf("migrations = %+v, want 0001_create_table then 0002_add_index", migrations)
+	}
+	if migrations[1].Down != "DROP INDEX a ON b;" {
+		t.Errorf("Down = %q", migrations[1].Down)
+	}
+}
+
+func TestLoadMigrationsRejectsBadSets(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{
+			"missing down",
+			fstest.MapFS{"m/0001_create_table.up.sql": {Data: []byte("CREATE TABLE b (c INT);")}},
+			"needs both an up and a down file",
+		},
+		{
+			"two names",
+			fstest.MapFS{
+				"m/0001_create_table.up.sql": {Data: []byte("CREATE TABLE b (c INT);")},
+				"m/0001_other.down.sql":      {Data: []byte("DROP TABLE b;")},
+			},
+			"has two names",
+		},
+		{
+			"bad file name",
+			fstest.MapFS{"m/create_table.sql": {Data: []byte("CREATE TABLE b (c INT);")}},
+			"unexpected migration file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadMigrations(tt.fsys, "m")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("loadMigrations error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// The embedded sets must always load, a bad file name would stop the server at boot
+func TestEmbeddedMigrations(t *testing.T) {
+	counts := make(map[string]int)
+	for _, driver := range []string{DriverMySQL, DriverSQLite} {
+		migrations, err := loadMigrations(migrationFiles, path.Join("migrations", driver))
+		if err != nil {
+			t.Fatalf("loadMigrations %s: %v", driver, err)
+		}
+		for i, migration := range migrations {
+			if migration.Version != i+1 {
+				t.Errorf("%s migration %d_%s is out of sequence, want version %d", driver, migration.Version, migration.Name, i+1)
+			}
+		}
+		counts[driver] = len(migrations)
+	}
+	// Every schema change needs a version for each driver
+	if counts[DriverMySQL] != counts[DriverSQLite] {
+		t.Errorf("mysql has %d migrations and sqlite %d, want the same", counts[DriverMySQL], counts[DriverSQLite])
+	}
+}
+
+func TestMigrateUpDownSQLite(t *testing.T) {
+	db, err := NewConnection(config.DatabaseConfig{
+		Driver:     DriverSQLite,
+		SQLitePath: filepath.Join(t.TempDir(), "ordersdb.sqlite"),
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	testMigrateUpDown(t, db, DriverSQLite)
+}
+
+// TestMigrateUpDownMySQL - Runs on a scratch database on the TEST_MYSQL_DSN server
+func TestMigrateUpDownMySQL(t *testing.T) {
+	testMigrateUpDown(t, scratchMySQL(t), DriverMySQL)
+}
+
+// scratchMySQL - A new database on the TEST_MYSQL_DSN server, dropped when the test ends
+func scratchMySQL(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN is not set")
+	}
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatalf("invalid TEST_MYSQL_DSN: %v", err)
+	}
+	cfg.DBName = ""
+	cfg.ParseTime = true
+
+	admin, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+	name := fmt.Sprintf("ordersdb_migrate_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP DATABASE " + name) })
+
+	cfg.DBName = name
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func testMigrateUpDown(t *testing.T, db *sql.DB, driver string) {
+	migrator, err := NewMigrator(db, driver)
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	// A second run has nothing left to do
+	applied, err := migrator.Up(ctx)
+	if err != nil || len(applied) != 0 {
+		t.Fatalf("second Up = %v, %v, want nothing applied", applied, err)
+	}
+
+	total := len(migrator.migrations)
+	reverted, err := migrator.Down(ctx, total)
+	if err != nil || len(reverted) != total {
+		t.Fatalf("Down = %d reverted, %v, want %d", len(reverted), err, total)
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			t.Errorf("migration %d still applied after Down", status.Version)
+		}
+	}
+}
+
+// baselineSchema - The tables the old database/schema.sql created, per driver
+var baselineSchema = map[string]string{
+	DriverMySQL: `CREATE TABLE locations (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		name VARCHAR(100) NOT NULL,
+		latitude DOUBLE NOT NULL,
+		longitude DOUBLE NOT NULL,
+		UNIQUE(name, latitude, longitude)
+	);
+	CREATE TABLE orders (
+		orderId INT AUTO_INCREMENT PRIMARY KEY,
+		resLocationId INT NOT NULL,
+		cusLocationId INT NOT NULL,
+		prepTimeInMinutes DOUBLE NOT NULL,
+		createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		FOREIGN KEY (resLocationId) REFERENCES locations(id),
+		FOREIGN KEY (cusLocationId) REFERENCES locations(id)
+	);
+	CREATE INDEX idx_orders_resLocationId ON orders (resLocationId);
+	CREATE INDEX idx_orders_cusLocationId ON orders (cusLocationId);`,
+	DriverSQLite: `CREATE TABLE locations (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name VARCHAR(100) NOT NULL,
+		latitude DOUBLE NOT NULL,
+		longitude DOUBLE NOT NULL,
+		UNIQUE(name, latitude, longitude)
+	);
+	CREATE TABLE orders (
+		orderId INTEGER PRIMARY KEY AUTOINCREMENT,
+		resLocationId INTEGER NOT NULL REFERENCES locations(id),
+		cusLocationId INTEGER NOT NULL REFERENCES locations(id),
+		prepTimeInMinutes DOUBLE NOT NULL,
+		createdAt TIMESTAMP DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER)),
+		updatedAt TIMESTAMP DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER))
+	);
+	CREATE INDEX idx_orders_resLocationId ON orders (resLocationId);
+	CREATE INDEX idx_orders_cusLocationId ON orders (cusLocationId);`,
+}
+
+func TestMigrateFromBaselineSQLite(t *testing.T) {
+	db, err := NewConnection(config.DatabaseConfig{
+		Driver:     DriverSQLite,
+		SQLitePath: filepath.Join(t.TempDir(), "ordersdb.sqlite"),
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	testMigrateFromBaseline(t, db, DriverSQLite)
+}
+
+func TestMigrateFromBaselineMySQL(t *testing.T) {
+	testMigrateFromBaseline(t, scratchMySQL(t), DriverMySQL)
+}
+
+// A database made from the old schema.sql keeps its orders and gets every later column and table
+func testMigrateFromBaseline(t *testing.T, db *sql.DB, driver string) {
+	ctx := context.Background()
+	if err := execStatements(ctx, db, baselineSchema[driver]); err != nil {
+		t.Fatalf("failed to create the baseline tables: %v", err)
+	}
+	statements := []string{
+		`INSERT INTO locations (name, latitude, longitude) VALUES ('Truffles', 12.962, 77.6386), ('Asha', 12.9352, 77.6245)`,
+		`INSERT INTO orders (resLocationId, cusLocationId, prepTimeInMinutes) VALUES (1, 2, 15)`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("failed to seed the baseline tables: %v", err)
+		}
+	}
+
+	migrator, err := NewMigrator(db, driver)
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(applied) != len(migrator.migrations) {
+		t.Errorf("applied %d migrations, want all %d", len(applied), len(migrator.migrations))
+	}
+
+	var status, locationType string
+	var size, changes int
+	row := db.QueryRowContext(ctx, `SELECT o.status, o.size, l.type FROM orders o JOIN locations l ON l.id = o.resLocationId WHERE o.orderId = 1`)
+	if err := row.Scan(&status, &size, &locationType); err != nil {
+		t.Fatalf("baseline order after Up: %v", err)
+	}
+	if status != "CREATED" || size != 1 || locationType != "ADDRESS" {
+		t.Errorf("baseline order = status %q, size %d, location type %q, want CREATED, 1, ADDRESS", status, size, locationType)
+	}
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM order_status_history WHERE orderId = 1`).Scan(&changes); err != nil || changes != 1 {
+		t.Errorf("baseline order history = %d rows, %v, want its backfilled creation", changes, err)
+	}
+	for _, table := range []string{"restaurants", "customers", "riders", "rider_order_assignments", "rider_route_plans"} {
+		if _, err := db.ExecContext(ctx, `SELECT COUNT(*) FROM `+table); err != nil {
+			t.Errorf("table %s after Up: %v", table, err)
+		}
+	}
+}
+
+// Reverting the backfill must not take creation rows placed orders wrote themselves
+func TestBackfillDownKeepsHistory(t *testing.T) {
+	db, err := NewConnection(config.DatabaseConfig{
+		Driver:     DriverSQLite,
+		SQLitePath: filepath.Join(t.TempDir(), "ordersdb.sqlite"),
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := NewMigrator(db, DriverSQLite)
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	statements := []string{
+		`INSERT INTO locations (name, latitude, longitude) VALUES ('Truffles', 12.962, 77.6386), ('Asha', 12.9352, 77.6245)`,
+		`INSERT INTO orders (resLocationId, cusLocationId, prepTimeInMinutes) VALUES (1, 2, 15)`,
+		`INSERT INTO order_status_history (orderId, fromStatus, toStatus) VALUES (1, '', 'CREATED')`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("failed to place an order: %v", err)
+		}
+	}
+
+	reverted, err := migrator.Down(ctx, 1)
+	if err != nil || len(reverted) != 1 || reverted[0].Name != "backfill_order_created_history" {
+		t.Fatalf("Down = %+v, %v, want the backfill reverted", reverted, err)
+	}
+	var changes int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM order_status_history WHERE orderId = 1`).Scan(&changes); err != nil || changes != 1 {
+		t.Errorf("order history after Down = %d rows, %v, want the creation row kept", changes, err)
+	}
+}
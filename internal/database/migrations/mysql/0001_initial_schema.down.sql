@@ -0,0 +1,3 @@
+-- Drop tables in reverse order of their foreign keys
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS locations;
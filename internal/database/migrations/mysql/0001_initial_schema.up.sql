@@ -1,6 +1,5 @@
--- Create database
-CREATE DATABASE IF NOT EXISTS ordersdb;
-USE ordersdb;
+-- The schema.sql tables, a database created from it already has them
+-- and only gets this version recorded
 
 -- Create locations table
 CREATE TABLE IF NOT EXISTS locations (
@@ -20,8 +19,7 @@ CREATE TABLE IF NOT EXISTS orders (
     createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     FOREIGN KEY (resLocationId) REFERENCES locations(id),
-    FOREIGN KEY (cusLocationId) REFERENCES locations(id)
+    FOREIGN KEY (cusLocationId) REFERENCES locations(id),
+    INDEX idx_orders_resLocationId (resLocationId),
+    INDEX idx_orders_cusLocationId (cusLocationId)
 );
-
-CREATE INDEX idx_orders_resLocationId ON orders (resLocationId);
-CREATE INDEX idx_orders_cusLocationId ON orders (cusLocationId);
\ No newline at end of file
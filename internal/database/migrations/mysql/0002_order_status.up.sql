@@ -0,0 +1,12 @@
+ALTER TABLE orders ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'CREATED';
+
+-- Create order status history table, one row per status transition
+CREATE TABLE order_status_history (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    orderId INT NOT NULL,
+    fromStatus VARCHAR(20) NOT NULL,
+    toStatus VARCHAR(20) NOT NULL,
+    changedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (orderId) REFERENCES orders(orderId),
+    INDEX idx_order_status_history_orderId (orderId)
+);
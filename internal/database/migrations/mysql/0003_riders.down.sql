@@ -0,0 +1,2 @@
+DROP TABLE rider_order_assignments;
+DROP TABLE riders;
@@ -0,0 +1,25 @@
+-- Create riders table, last known location is updated by GPS pings
+CREATE TABLE riders (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    vehicleType VARCHAR(20) NOT NULL,
+    capacity INT NOT NULL,
+    shiftStatus VARCHAR(20) NOT NULL DEFAULT 'OFF_SHIFT',
+    lastLatitude DOUBLE NULL,
+    lastLongitude DOUBLE NULL,
+    lastLocationAt TIMESTAMP NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+-- Create rider order assignments table, an order is with at most one rider
+CREATE TABLE rider_order_assignments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    riderId INT NOT NULL,
+    orderId INT NOT NULL,
+    assignedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(orderId),
+    FOREIGN KEY (riderId) REFERENCES riders(id),
+    FOREIGN KEY (orderId) REFERENCES orders(orderId),
+    INDEX idx_rider_order_assignments_riderId (riderId)
+);
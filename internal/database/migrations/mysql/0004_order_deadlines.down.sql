@@ -0,0 +1,3 @@
+ALTER TABLE orders
+    DROP COLUMN promisedBy,
+    DROP COLUMN slaWeight;
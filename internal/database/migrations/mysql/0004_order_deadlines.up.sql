@@ -0,0 +1,3 @@
+ALTER TABLE orders
+    ADD COLUMN promisedBy TIMESTAMP NULL,
+    ADD COLUMN slaWeight DOUBLE NOT NULL DEFAULT 1;
@@ -0,0 +1,6 @@
+ALTER TABLE riders DROP COLUMN maxWeightKg;
+
+ALTER TABLE orders
+    DROP COLUMN size,
+    DROP COLUMN weightKg,
+    DROP COLUMN maxInBagMinutes;
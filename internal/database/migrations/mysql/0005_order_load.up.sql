@@ -0,0 +1,6 @@
+ALTER TABLE orders
+    ADD COLUMN size INT NOT NULL DEFAULT 1,
+    ADD COLUMN weightKg DOUBLE NOT NULL DEFAULT 0,
+    ADD COLUMN maxInBagMinutes DOUBLE NOT NULL DEFAULT 0;
+
+ALTER TABLE riders ADD COLUMN maxWeightKg DOUBLE NOT NULL DEFAULT 0;
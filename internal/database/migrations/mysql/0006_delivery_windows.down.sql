@@ -0,0 +1,3 @@
+ALTER TABLE orders
+    DROP COLUMN deliverAfter,
+    DROP COLUMN deliverBefore;
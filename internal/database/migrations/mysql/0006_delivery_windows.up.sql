@@ -0,0 +1,3 @@
+ALTER TABLE orders
+    ADD COLUMN deliverAfter TIMESTAMP NULL,
+    ADD COLUMN deliverBefore TIMESTAMP NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE locations
+    DROP INDEX idx_locations_type,
+    DROP COLUMN type;
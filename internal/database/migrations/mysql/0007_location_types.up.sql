@@ -0,0 +1,3 @@
+ALTER TABLE locations
+    ADD COLUMN type VARCHAR(20) NOT NULL DEFAULT 'ADDRESS',
+    ADD INDEX idx_locations_type (type);
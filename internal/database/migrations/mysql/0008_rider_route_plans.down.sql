@@ -0,0 +1 @@
+DROP TABLE rider_route_plans;
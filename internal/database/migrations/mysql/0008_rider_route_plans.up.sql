@@ -0,0 +1,15 @@
+-- Create rider route plans table, every re-plan of a rider adds a version
+CREATE TABLE rider_route_plans (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    riderId INT NOT NULL,
+    version INT NOT NULL,
+    reason VARCHAR(20) NOT NULL,
+    plannedAt TIMESTAMP NOT NULL,
+    startLatitude DOUBLE NOT NULL,
+    startLongitude DOUBLE NOT NULL,
+    totalTimeMinutes DOUBLE NOT NULL,
+    steps JSON NOT NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(riderId, version),
+    FOREIGN KEY (riderId) REFERENCES riders(id)
+);
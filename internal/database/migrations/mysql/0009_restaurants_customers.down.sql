@@ -0,0 +1,11 @@
+-- The foreign keys go first, MySQL won't drop a column one uses
+ALTER TABLE orders
+    DROP FOREIGN KEY fk_orders_restaurantId,
+    DROP FOREIGN KEY fk_orders_customerId;
+
+ALTER TABLE orders
+    DROP COLUMN restaurantId,
+    DROP COLUMN customerId;
+
+DROP TABLE customers;
+DROP TABLE restaurants;
@@ -0,0 +1,26 @@
+-- Create restaurants table, one restaurant per location
+CREATE TABLE restaurants (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    locationId INT NOT NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(locationId),
+    FOREIGN KEY (locationId) REFERENCES locations(id)
+);
+
+-- Create customers table
+CREATE TABLE customers (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    phone VARCHAR(20) NOT NULL DEFAULT '',
+    locationId INT NOT NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (locationId) REFERENCES locations(id)
+);
+
+-- Orders placed with coordinates only have neither
+ALTER TABLE orders
+    ADD COLUMN restaurantId INT NULL,
+    ADD COLUMN customerId INT NULL,
+    ADD CONSTRAINT fk_orders_restaurantId FOREIGN KEY (restaurantId) REFERENCES restaurants(id),
+    ADD CONSTRAINT fk_orders_customerId FOREIGN KEY (customerId) REFERENCES customers(id);
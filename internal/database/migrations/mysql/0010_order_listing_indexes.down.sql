@@ -0,0 +1,4 @@
+DROP INDEX idx_locations_latitude_longitude ON locations;
+DROP INDEX idx_orders_restaurantId_createdAt ON orders;
+DROP INDEX idx_orders_status_createdAt ON orders;
+DROP INDEX idx_orders_createdAt ON orders;
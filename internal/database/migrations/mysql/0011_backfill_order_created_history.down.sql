@@ -0,0 +1,2 @@
+-- Nothing to undo: backfilled creation rows can't be told apart from the
+-- ones orders write when they are placed, so both are kept.
@@ -0,0 +1,7 @@
+-- Orders placed before creation was recorded get a CREATED row at their creation time
+INSERT INTO order_status_history (orderId, fromStatus, toStatus, changedAt)
+SELECT o.orderId, '', 'CREATED', o.createdAt
+FROM orders o
+WHERE NOT EXISTS (
+    SELECT 1 FROM order_status_history h WHERE h.orderId = o.orderId AND h.fromStatus = ''
+);
@@ -0,0 +1,4 @@
+-- Drop tables in reverse order of their foreign keys
+DROP TRIGGER IF EXISTS orders_updatedAt;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS locations;
@@ -0,0 +1,30 @@
+-- SQLite version of the MySQL schema. Times are unix milliseconds, the
+-- driver writes them that way, and triggers stand in for ON UPDATE.
+
+-- Create locations table
+CREATE TABLE IF NOT EXISTS locations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(100) NOT NULL,
+    latitude DOUBLE NOT NULL,
+    longitude DOUBLE NOT NULL,
+    UNIQUE(name, latitude, longitude)
+);
+
+-- Create orders table
+CREATE TABLE IF NOT EXISTS orders (
+    orderId INTEGER PRIMARY KEY AUTOINCREMENT,
+    resLocationId INTEGER NOT NULL REFERENCES locations(id),
+    cusLocationId INTEGER NOT NULL REFERENCES locations(id),
+    prepTimeInMinutes DOUBLE NOT NULL,
+    createdAt TIMESTAMP DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER)),
+    updatedAt TIMESTAMP DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER))
+);
+
+CREATE INDEX IF NOT EXISTS idx_orders_resLocationId ON orders (resLocationId);
+CREATE INDEX IF NOT EXISTS idx_orders_cusLocationId ON orders (cusLocationId);
+
+CREATE TRIGGER IF NOT EXISTS orders_updatedAt AFTER UPDATE ON orders
+FOR EACH ROW WHEN NEW.updatedAt = OLD.updatedAt
+BEGIN
+    UPDATE orders SET updatedAt = (CAST(unixepoch('subsec') * 1000 AS INTEGER)) WHERE orderId = NEW.orderId;
+END;
@@ -0,0 +1,12 @@
+ALTER TABLE orders ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'CREATED';
+
+-- Create order status history table, one row per status transition
+CREATE TABLE order_status_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    orderId INTEGER NOT NULL REFERENCES orders(orderId),
+    fromStatus VARCHAR(20) NOT NULL,
+    toStatus VARCHAR(20) NOT NULL,
+    changedAt TIMESTAMP DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER))
+);
+
+CREATE INDEX idx_order_status_history_orderId ON order_status_history (orderId);
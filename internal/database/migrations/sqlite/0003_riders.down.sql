@@ -0,0 +1,3 @@
+DROP TABLE rider_order_assignments;
+DROP TRIGGER riders_updatedAt;
+DROP TABLE riders;
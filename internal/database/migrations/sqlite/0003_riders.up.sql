@@ -0,0 +1,30 @@
+-- Create riders table, last known location is updated by GPS pings
+CREATE TABLE riders (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(100) NOT NULL,
+    vehicleType VARCHAR(20) NOT NULL,
+    capacity INTEGER NOT NULL,
+    shiftStatus VARCHAR(20) NOT NULL DEFAULT 'OFF_SHIFT',
+    lastLatitude DOUBLE NULL,
+    lastLongitude DOUBLE NULL,
+    lastLocationAt TIMESTAMP NULL,
+    createdAt TIMESTAMP DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER)),
+    updatedAt TIMESTAMP DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER))
+);
+
+CREATE TRIGGER riders_updatedAt AFTER UPDATE ON riders
+FOR EACH ROW WHEN NEW.updatedAt = OLD.updatedAt
+BEGIN
+    UPDATE riders SET updatedAt = (CAST(unixepoch('subsec') * 1000 AS INTEGER)) WHERE id = NEW.id;
+END;
+
+-- Create rider order assignments table, an order is with at most one rider
+CREATE TABLE rider_order_assignments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    riderId INTEGER NOT NULL REFERENCES riders(id),
+    orderId INTEGER NOT NULL REFERENCES orders(orderId),
+    assignedAt TIMESTAMP DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER)),
+    UNIQUE(orderId)
+);
+
+CREATE INDEX idx_rider_order_assignments_riderId ON rider_order_assignments (riderId);
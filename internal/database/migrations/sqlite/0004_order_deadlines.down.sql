@@ -0,0 +1,2 @@
+ALTER TABLE orders DROP COLUMN promisedBy;
+ALTER TABLE orders DROP COLUMN slaWeight;
@@ -0,0 +1,2 @@
+ALTER TABLE orders ADD COLUMN promisedBy TIMESTAMP NULL;
+ALTER TABLE orders ADD COLUMN slaWeight DOUBLE NOT NULL DEFAULT 1;
@@ -0,0 +1,5 @@
+ALTER TABLE riders DROP COLUMN maxWeightKg;
+
+ALTER TABLE orders DROP COLUMN size;
+ALTER TABLE orders DROP COLUMN weightKg;
+ALTER TABLE orders DROP COLUMN maxInBagMinutes;
@@ -0,0 +1,5 @@
+ALTER TABLE orders ADD COLUMN size INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE orders ADD COLUMN weightKg DOUBLE NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN maxInBagMinutes DOUBLE NOT NULL DEFAULT 0;
+
+ALTER TABLE riders ADD COLUMN maxWeightKg DOUBLE NOT NULL DEFAULT 0;
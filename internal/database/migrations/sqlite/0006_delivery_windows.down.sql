@@ -0,0 +1,2 @@
+ALTER TABLE orders DROP COLUMN deliverAfter;
+ALTER TABLE orders DROP COLUMN deliverBefore;
@@ -0,0 +1,2 @@
+ALTER TABLE orders ADD COLUMN deliverAfter TIMESTAMP NULL;
+ALTER TABLE orders ADD COLUMN deliverBefore TIMESTAMP NULL;
@@ -0,0 +1,3 @@
+DROP INDEX idx_locations_type;
+
+ALTER TABLE locations DROP COLUMN type;
@@ -0,0 +1,3 @@
+ALTER TABLE locations ADD COLUMN type VARCHAR(20) NOT NULL DEFAULT 'ADDRESS';
+
+CREATE INDEX idx_locations_type ON locations (type);
@@ -0,0 +1,14 @@
+-- Create rider route plans table, every re-plan of a rider adds a version
+CREATE TABLE rider_route_plans (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    riderId INTEGER NOT NULL REFERENCES riders(id),
+    version INTEGER NOT NULL,
+    reason VARCHAR(20) NOT NULL,
+    plannedAt TIMESTAMP NOT NULL,
+    startLatitude DOUBLE NOT NULL,
+    startLongitude DOUBLE NOT NULL,
+    totalTimeMinutes DOUBLE NOT NULL,
+    steps TEXT NOT NULL,
+    createdAt TIMESTAMP DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER)),
+    UNIQUE(riderId, version)
+);
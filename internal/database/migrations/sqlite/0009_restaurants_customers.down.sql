@@ -0,0 +1,5 @@
+ALTER TABLE orders DROP COLUMN restaurantId;
+ALTER TABLE orders DROP COLUMN customerId;
+
+DROP TABLE customers;
+DROP TABLE restaurants;
@@ -0,0 +1,21 @@
+-- Create restaurants table, one restaurant per location
+CREATE TABLE restaurants (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(100) NOT NULL,
+    locationId INTEGER NOT NULL REFERENCES locations(id),
+    createdAt TIMESTAMP DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER)),
+    UNIQUE(locationId)
+);
+
+-- Create customers table
+CREATE TABLE customers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(100) NOT NULL,
+    phone VARCHAR(20) NOT NULL DEFAULT '',
+    locationId INTEGER NOT NULL REFERENCES locations(id),
+    createdAt TIMESTAMP DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER))
+);
+
+-- Orders placed with coordinates only have neither
+ALTER TABLE orders ADD COLUMN restaurantId INTEGER NULL REFERENCES restaurants(id);
+ALTER TABLE orders ADD COLUMN customerId INTEGER NULL REFERENCES customers(id);
@@ -0,0 +1,4 @@
+DROP INDEX idx_locations_latitude_longitude;
+DROP INDEX idx_orders_restaurantId_createdAt;
+DROP INDEX idx_orders_status_createdAt;
+DROP INDEX idx_orders_createdAt;
@@ -0,0 +1,5 @@
+-- Order listing, the rowid orderId is appended to each index for keyset paging
+CREATE INDEX idx_orders_createdAt ON orders (createdAt);
+CREATE INDEX idx_orders_status_createdAt ON orders (status, createdAt);
+CREATE INDEX idx_orders_restaurantId_createdAt ON orders (restaurantId, createdAt);
+CREATE INDEX idx_locations_latitude_longitude ON locations (latitude, longitude);
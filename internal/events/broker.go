@@ -0,0 +1,69 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types published by the services
+const (
+	TypeOrdersAssigned = "orders_assigned"
+	TypeRouteChanged   = "route_changed"
+)
+
+// Event - Something that happened which stream subscribers are told about
+type Event struct {
+	Type string      `json:"type"`
+	At   time.Time   `json:"at"`
+	Data interface{} `json:"data"`
+}
+
+// subscriberBuffer - Events a slow subscriber can fall behind by before events are dropped for it
+const subscriberBuffer = 64
+
+/*
+* Broker - In-process fan out of events to every subscriber.
+* Publish never blocks, a subscriber whose buffer is full misses the event.
+ */
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Subscribe - Returns the event channel and a func that unsubscribes and closes it
+func (b *Broker) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func (b *Broker) Publish(eventType string, data interface{}) {
+	event := Event{Type: eventType, At: time.Now(), Data: data}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	orderModel "github.com/SHIVAMSINGH0101/go-demo/internal/models"
+	orderService "github.com/SHIVAMSINGH0101/go-demo/internal/services"
+	"github.com/gorilla/mux"
+)
+
+type CustomerHandler struct {
+	Service orderService.OrderService
+}
+
+func NewCustomerHandler(service orderService.OrderService) *CustomerHandler {
+	return &CustomerHandler{
+		Service: service,
+	}
+}
+
+func (h *CustomerHandler) RegisterCustomerHandlers(r *mux.Router) {
+	r.HandleFunc("/customer/create", h.CreateCustomer).Methods("POST")
+	r.HandleFunc("/customer/{id}", h.GetCustomer).Methods("GET")
+}
+
+// CreateCustomerRequest - Customer with the address orders are delivered to
+type CreateCustomerRequest struct {
+	Name  string  `json:"name"`
+	Phone string  `json:"phone"`
+	Lat   float64 `json:"lat"`
+	Lon   float64 `json:"lon"`
+}
+
+func (h *CustomerHandler) CreateCustomer(w http.ResponseWriter, r *http.Request) {
+	var req CreateCustomerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, "invalid request")
+		return
+	}
+
+	customer := &orderModel.Customer{
+		Name:  req.Name,
+		Phone: req.Phone,
+		Location: orderModel.Location{
+			Latitude:  req.Lat,
+			Longitude: req.Lon,
+		},
+	}
+	customerId, err := h.Service.CreateCustomer(r.Context(), customer)
+	if err != nil {
+		writeError(w, "failed to create customer", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     "created",
+		"customerId": customerId,
+		"locationId": customer.LocationID,
+	})
+}
+
+func (h *CustomerHandler) GetCustomer(w http.ResponseWriter, r *http.Request) {
+	customerId, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeBadRequest(w, "invalid customer id")
+		return
+	}
+
+	customer, err := h.Service.GetCustomerByID(r.Context(), customerId)
+	if err != nil {
+		writeError(w, "failed to fetch customer", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(customer)
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	orderService "github.com/SHIVAMSINGH0101/go-demo/internal/services"
+	"github.com/gorilla/mux"
+)
+
+type DispatchHandler struct {
+	Service orderService.DispatchService
+}
+
+func NewDispatchHandler(service orderService.DispatchService) *DispatchHandler {
+	return &DispatchHandler{
+		Service: service,
+	}
+}
+
+func (h *DispatchHandler) RegisterDispatchHandlers(r *mux.Router) {
+	r.HandleFunc("/dispatch/run", h.RunDispatch).Methods("POST")
+	r.HandleFunc("/dispatch/assignments", h.ListAssignments).Methods("GET")
+}
+
+// RunDispatch - Runs a dispatch tick now instead of waiting for the next one
+func (h *DispatchHandler) RunDispatch(w http.ResponseWriter, r *http.Request) {
+	result, err := h.Service.RunOnce(r.Context())
+	if err != nil {
+		writeError(w, "failed to run dispatch", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// ListAssignments - Rider order assignments since the `since` timestamp, last hour by default
+func (h *DispatchHandler) ListAssignments(w http.ResponseWriter, r *http.Request) {
+	since := time.Now().Add(-time.Hour)
+	if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
+		parsed, err := time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			writeBadRequest(w, "invalid since, expected RFC3339 timestamp")
+			return
+		}
+		since = parsed
+	}
+
+	assignments, err := h.Service.ListAssignments(r.Context(), since)
+	if err != nil {
+		writeError(w, "failed to list assignments", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"since":       since,
+		"assignments": assignments,
+	})
+}
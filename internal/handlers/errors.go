@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/apperrors"
+)
+
+// ErrorResponse - Body of every error response
+type ErrorResponse struct {
+	// Error is bad_request, not_found, conflict, validation_failed or internal
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// errBadRequest - The request could not be read, e.g. malformed JSON or a non numeric id
+var errBadRequest = errors.New("bad request")
+
+/*
+* writeError - The single place errors become responses. Domain errors map
+* to 404, 409 and 422 with their message, anything else is logged with msg
+* and answered with a 500 carrying only msg.
+ */
+func writeError(w http.ResponseWriter, msg string, err error) {
+	status, code, message := http.StatusInternalServerError, "internal", msg
+	switch {
+	case errors.Is(err, errBadRequest):
+		status, code, message = http.StatusBadRequest, "bad_request", err.Error()
+	case errors.Is(err, apperrors.ErrNotFound):
+		status, code, message = http.StatusNotFound, "not_found", err.Error()
+	case errors.Is(err, apperrors.ErrConflict):
+		status, code, message = http.StatusConflict, "conflict", err.Error()
+	case errors.Is(err, apperrors.ErrValidation):
+		status, code, message = http.StatusUnprocessableEntity, "validation_failed", err.Error()
+	default:
+		log.Printf("%s, err %+v", msg, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Error:   code,
+		Message: message,
+	})
+}
+
+// writeBadRequest - Answers a request that could not be read with a 400
+func writeBadRequest(w http.ResponseWriter, message string) {
+	writeError(w, message, apperrors.New(errBadRequest, message))
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/apperrors"
+)
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{"bad request", fmt.Errorf("%w: invalid order id", errBadRequest), http.StatusBadRequest, "bad_request", "bad request: invalid order id"},
+		{"not found", apperrors.New(apperrors.ErrNotFound, "order not found"), http.StatusNotFound, "not_found", "order not found"},
+		{"conflict", fmt.Errorf("%w: 3", apperrors.New(apperrors.ErrConflict, "order is assigned")), http.StatusConflict, "conflict", "order is assigned: 3"},
+		{"validation", apperrors.New(apperrors.ErrValidation, "name is required"), http.StatusUnprocessableEntity, "validation_failed", "name is required"},
+		// Anything else keeps its details out of the response
+		{"internal", errors.New("dial tcp 10.0.0.5:3306: connection refused"), http.StatusInternalServerError, "internal", "failed to fetch order"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeError(rec, "failed to fetch order", tt.err)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("content type = %q, want application/json", got)
+			}
+			var body ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			if body.Error != tt.code || body.Message != tt.message {
+				t.Errorf("body = %+v, want %s %q", body, tt.code, tt.message)
+			}
+		})
+	}
+}
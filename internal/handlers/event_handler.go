@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/events"
+	"github.com/gorilla/mux"
+)
+
+type EventHandler struct {
+	Broker *events.Broker
+}
+
+func NewEventHandler(broker *events.Broker) *EventHandler {
+	return &EventHandler{
+		Broker: broker,
+	}
+}
+
+func (h *EventHandler) RegisterEventHandlers(r *mux.Router) {
+	r.HandleFunc("/events", h.StreamEvents).Methods("GET")
+}
+
+/*
+* StreamEvents : Server-Sent Events stream of everything published on the broker.
+* `types` optionally limits it to a comma separated list of event types.
+ */
+func (h *EventHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, "streaming not supported", fmt.Errorf("%T can not flush", w))
+		return
+	}
+
+	types := make(map[string]struct{})
+	if typesStr := r.URL.Query().Get("types"); typesStr != "" {
+		for _, t := range strings.Split(typesStr, ",") {
+			types[strings.TrimSpace(t)] = struct{}{}
+		}
+	}
+
+	ch, unsubscribe := h.Broker.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-ch:
+			if len(types) > 0 {
+				if _, ok := types[event.Type]; !ok {
+					continue
+				}
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	orderModel "github.com/SHIVAMSINGH0101/go-demo/internal/models"
+	orderService "github.com/SHIVAMSINGH0101/go-demo/internal/services"
+	"github.com/gorilla/mux"
+)
+
+type HubHandler struct {
+	Service orderService.OrderService
+}
+
+func NewHubHandler(service orderService.OrderService) *HubHandler {
+	return &HubHandler{
+		Service: service,
+	}
+}
+
+func (h *HubHandler) RegisterHubHandlers(r *mux.Router) {
+	r.HandleFunc("/hub/create", h.CreateHub).Methods("POST")
+	r.HandleFunc("/hubs", h.GetHubs).Methods("GET")
+}
+
+// CreateHubRequest - A hub or dark store riders start or end their shift at
+type CreateHubRequest struct {
+	Name string  `json:"name"`
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+}
+
+func (h *HubHandler) CreateHub(w http.ResponseWriter, r *http.Request) {
+	var req CreateHubRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, "invalid request")
+		return
+	}
+
+	hubId, err := h.Service.CreateHub(r.Context(), &orderModel.Location{
+		Name:      req.Name,
+		Latitude:  req.Lat,
+		Longitude: req.Lon,
+	})
+	if err != nil {
+		writeError(w, "failed to create hub", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     "created",
+		"locationId": hubId,
+	})
+}
+
+// GetHubs - Lists every hub, their ids are what route requests take as start or end
+func (h *HubHandler) GetHubs(w http.ResponseWriter, r *http.Request) {
+	hubs, err := h.Service.GetHubs(r.Context())
+	if err != nil {
+		writeError(w, "failed to fetch hubs", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"hubs": hubs,
+	})
+}
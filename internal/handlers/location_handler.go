@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	orderService "github.com/SHIVAMSINGH0101/go-demo/internal/services"
+	"github.com/gorilla/mux"
+)
+
+type LocationHandler struct {
+	Service orderService.OrderService
+}
+
+func NewLocationHandler(service orderService.OrderService) *LocationHandler {
+	return &LocationHandler{
+		Service: service,
+	}
+}
+
+func (h *LocationHandler) RegisterLocationHandlers(r *mux.Router) {
+	r.HandleFunc("/location/{id}", h.GetLocation).Methods("GET")
+}
+
+func (h *LocationHandler) GetLocation(w http.ResponseWriter, r *http.Request) {
+	locationId, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeBadRequest(w, "invalid location id")
+		return
+	}
+
+	loc, err := h.Service.GetLocationByID(r.Context(), locationId)
+	if err != nil {
+		writeError(w, "failed to fetch location", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(loc)
+}
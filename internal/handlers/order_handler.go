@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
@@ -17,18 +16,25 @@ import (
 )
 
 type OrderHandler struct {
-	Service orderService.OrderService
+	Service      orderService.OrderService
+	RouteService orderService.RouteService
 }
 
-func NewOrderHandler(service orderService.OrderService) *OrderHandler {
+func NewOrderHandler(service orderService.OrderService, routeService orderService.RouteService) *OrderHandler {
 	return &OrderHandler{
-		Service: service,
+		Service:      service,
+		RouteService: routeService,
 	}
 }
 
 func (h *OrderHandler) RegisterOrderHandlers(r *mux.Router) {
 	r.HandleFunc("/order/create", h.CreateOrder).Methods("POST")
 	r.HandleFunc("/order/best_route", h.GetBestRoute).Methods("GET")
+	r.HandleFunc("/order/insertion", h.GetInsertions).Methods("POST")
+	r.HandleFunc("/order/{id}", h.GetOrder).Methods("GET")
+	r.HandleFunc("/order/{id}/status", h.UpdateOrderStatus).Methods("POST")
+	r.HandleFunc("/order/{id}/history", h.GetOrderStatusHistory).Methods("GET")
+	r.HandleFunc("/orders", h.ListOrders).Methods("GET")
 
 	// Motive
 	r.HandleFunc("/vechiles/discontinued", h.GetDiscontinuedVehicles).Methods("GET")
@@ -37,8 +43,11 @@ func (h *OrderHandler) RegisterOrderHandlers(r *mux.Router) {
 /*
 * CreateOrderRequest - This object stores Restaurant and Customer
 * location information. The unique row id is taken as orderId.
-*/
+* A stored restaurant or customer can be given by id instead of coordinates.
+ */
 type CreateOrderRequest struct {
+	RestaurantID   *int64  `json:"restaurant_id"`
+	CustomerID     *int64  `json:"customer_id"`
 	RestaurantName string  `json:"restaurant_name"`
 	RestaurantLat  float64 `json:"restaurant_lat"`
 	RestaurantLon  float64 `json:"restaurant_lon"`
@@ -46,144 +55,504 @@ type CreateOrderRequest struct {
 	CustomerLat    float64 `json:"customer_lat"`
 	CustomerLon    float64 `json:"customer_lon"`
 	PrepTimeMin    float64 `json:"prep_time_minutes"`
+	// PromisedBy - Optional delivery deadline promised to the customer
+	PromisedBy *time.Time `json:"promised_by"`
+	// SLAWeight - Optional weight of this order's lateness, 1 by default
+	SLAWeight float64 `json:"sla_weight"`
+	// Size, weight and in-bag limit - Optional, for rider capacity and food safety
+	Size            int     `json:"size"`
+	WeightKg        float64 `json:"weight_kg"`
+	MaxInBagMinutes float64 `json:"max_in_bag_minutes"`
+	// DeliverAfter, DeliverBefore - Optional delivery window for scheduled orders
+	DeliverAfter  *time.Time `json:"deliver_after"`
+	DeliverBefore *time.Time `json:"deliver_before"`
 }
 
 /*
 * CreateOrder : This API creates Order in our DB
-*/
+ */
 func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	var req CreateOrderRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request", http.StatusBadRequest)
+		writeBadRequest(w, "invalid request")
 		return
 	}
 
-	restaurant := &orderModel.Location{
-		Name:      req.RestaurantName,
-		Latitude:  req.RestaurantLat,
-		Longitude: req.RestaurantLon,
+	orderId, err := h.Service.PlaceOrder(r.Context(), &orderService.PlaceOrderRequest{
+		RestaurantID: req.RestaurantID,
+		Restaurant: orderModel.Location{
+			Name:      req.RestaurantName,
+			Latitude:  req.RestaurantLat,
+			Longitude: req.RestaurantLon,
+		},
+		CustomerID: req.CustomerID,
+		Customer: orderModel.Location{
+			Name:      req.CustomerName,
+			Latitude:  req.CustomerLat,
+			Longitude: req.CustomerLon,
+		},
+		Order: orderModel.Order{
+			PrepTimeInMinutes: req.PrepTimeMin,
+			PromisedBy:        req.PromisedBy,
+			SLAWeight:         req.SLAWeight,
+			Size:              req.Size,
+			WeightKg:          req.WeightKg,
+			MaxInBagMinutes:   req.MaxInBagMinutes,
+			DeliverAfter:      req.DeliverAfter,
+			DeliverBefore:     req.DeliverBefore,
+		},
+	})
+	if err != nil {
+		writeError(w, "failed to create order", err)
+		return
 	}
-	customer := &orderModel.Location{
-		Name:      req.CustomerName,
-		Latitude:  req.CustomerLat,
-		Longitude: req.CustomerLon,
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "created",
+		"orderId": orderId,
+	})
+}
+
+// GetOrder - Returns the order with its restaurant and customer locations
+func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	orderId, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeBadRequest(w, "invalid order id")
+		return
 	}
 
-	// Save restaurant location - In production restaurant location will be mapped to resId
-	resID, err := h.Service.CreateLocation(restaurant)
+	order, err := h.Service.GetOrderDetails(r.Context(), orderId)
 	if err != nil {
-		log.Printf("failed to save restaurant, err %+v", err)
-		http.Error(w, "failed to save restaurant", http.StatusInternalServerError)
+		writeError(w, "failed to fetch order", err)
 		return
 	}
 
-	// Save customer location - In production customer location will be mapped to cusId
-	cusID, err := h.Service.CreateLocation(customer)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// UpdateOrderStatusRequest - Target status of the order
+type UpdateOrderStatusRequest struct {
+	Status orderModel.OrderStatus `json:"status"`
+}
+
+/*
+* UpdateOrderStatus : Moves an order along its lifecycle
+* CREATED -> ACCEPTED -> PREPARING -> READY -> PICKED_UP -> DELIVERED,
+* any state before PICKED_UP can also go to CANCELLED
+ */
+func (h *OrderHandler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	orderId, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
 	if err != nil {
-		log.Printf("failed to save customer, err %+v", err)
-		http.Error(w, "failed to save customer", http.StatusInternalServerError)
+		writeBadRequest(w, "invalid order id")
 		return
 	}
 
-	order := &orderModel.Order{
-		ResLocationID: resID,
-		CusLocationID:  cusID,
-		PrepTimeInMinutes: req.PrepTimeMin,
+	var req UpdateOrderStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, "invalid request")
+		return
 	}
-	orderId, err := h.Service.CreateOrder(order)
+
+	order, err := h.Service.UpdateOrderStatus(r.Context(), orderId, req.Status)
 	if err != nil {
-		log.Printf("failed to create order, err %+v", err)
-		http.Error(w, "failed to create order", http.StatusInternalServerError)
+		writeError(w, "failed to update order status", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// GetOrderStatusHistory - Returns every status transition of an order with its timestamp
+func (h *OrderHandler) GetOrderStatusHistory(w http.ResponseWriter, r *http.Request) {
+	orderId, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeBadRequest(w, "invalid order id")
+		return
+	}
+
+	history, err := h.Service.GetOrderStatusHistory(r.Context(), orderId)
+	if err != nil {
+		writeError(w, "failed to fetch order history", err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":  "created",
 		"orderId": orderId,
+		"history": history,
 	})
 }
 
-// GetBestRoute - Returns optimal path for the delivery partner
-func (h *OrderHandler) GetBestRoute(w http.ResponseWriter, r *http.Request) {
-	latStr := r.URL.Query().Get("lat")
-	lonStr := r.URL.Query().Get("lon")
+/*
+* ListOrders - Pages through orders. Filters are created_from and created_to
+* (RFC3339), status (comma separated), restaurant_id, restaurant_location_id
+* and bbox=min_lat,min_lon,max_lat,max_lon around the customer. sort is id,
+* -id, created_at or -created_at, the next page is asked for with cursor.
+ */
+func (h *OrderHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := orderModel.OrderFilter{
+		Sort: orderModel.OrderSort(query.Get("sort")),
+	}
 
-	lat, err1 := strconv.ParseFloat(latStr, 64)
-	lon, err2 := strconv.ParseFloat(lonStr, 64)
+	if fromStr := query.Get("created_from"); fromStr != "" {
+		from, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			writeBadRequest(w, "invalid created_from, expected RFC3339 timestamp")
+			return
+		}
+		filter.CreatedFrom = &from
+	}
+	if toStr := query.Get("created_to"); toStr != "" {
+		to, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			writeBadRequest(w, "invalid created_to, expected RFC3339 timestamp")
+			return
+		}
+		filter.CreatedTo = &to
+	}
+	if statusStr := query.Get("status"); statusStr != "" {
+		for _, status := range strings.Split(statusStr, ",") {
+			filter.Statuses = append(filter.Statuses, orderModel.OrderStatus(strings.TrimSpace(status)))
+		}
+	}
+	if restaurantStr := query.Get("restaurant_id"); restaurantStr != "" {
+		restaurantId, err := strconv.ParseInt(restaurantStr, 10, 64)
+		if err != nil {
+			writeBadRequest(w, "invalid restaurant_id")
+			return
+		}
+		filter.RestaurantID = &restaurantId
+	}
+	if locationStr := query.Get("restaurant_location_id"); locationStr != "" {
+		locationId, err := strconv.ParseInt(locationStr, 10, 64)
+		if err != nil {
+			writeBadRequest(w, "invalid restaurant_location_id")
+			return
+		}
+		filter.RestaurantLocationID = &locationId
+	}
+	if bboxStr := query.Get("bbox"); bboxStr != "" {
+		box, err := parseBoundingBox(bboxStr)
+		if err != nil {
+			writeBadRequest(w, "invalid bbox, expected min_lat,min_lon,max_lat,max_lon")
+			return
+		}
+		filter.CustomerBox = box
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			writeBadRequest(w, "invalid limit")
+			return
+		}
+		filter.Limit = limit
+	}
 
-	if err1 != nil || err2 != nil {
-		http.Error(w, "invalid coordinates", http.StatusBadRequest)
+	page, err := h.Service.ListOrders(r.Context(), filter, query.Get("cursor"))
+	if err != nil {
+		writeError(w, "failed to list orders", err)
 		return
 	}
 
-	stringOrderIDs := strings.Split(r.URL.Query().Get("orderIds"), ",")
-	orderIDs := make([]int64, 0)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// parseBoundingBox - min_lat,min_lon,max_lat,max_lon
+func parseBoundingBox(raw string) (*orderModel.BoundingBox, error) {
+	parts := strings.Split(raw, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("expected 4 values, got %d", len(parts))
+	}
+	values := make([]float64, len(parts))
+	for i, part := range parts {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return &orderModel.BoundingBox{
+		MinLatitude:  values[0],
+		MinLongitude: values[1],
+		MaxLatitude:  values[2],
+		MaxLongitude: values[3],
+	}, nil
+}
+
+/*
+* GetBestRoute - Returns optimal path for the delivery partner.
+* Either riderId alone, or lat, lon and orderIds must be given.
+* start_location_id can replace lat and lon.
+ */
+func (h *OrderHandler) GetBestRoute(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := orderService.RouteRequest{
+		Vehicle:   query.Get("vehicle"),
+		Strategy:  query.Get("strategy"),
+		Objective: query.Get("objective"),
+	}
 
-	for _, id := range stringOrderIDs {
-		orderId, err := strconv.ParseInt(id, 10, 64)
+	// Path of the route - start at X, end at Y, round trip and required via stops
+	if startStr := query.Get("start_location_id"); startStr != "" {
+		startId, err := strconv.ParseInt(startStr, 10, 64)
 		if err != nil {
-			log.Printf("failed to covert orderId, err %+v", err)
-			http.Error(w, `failed to covert orderId `, http.StatusBadRequest)
+			writeBadRequest(w, "invalid start_location_id")
 			return
 		}
-		orderIDs = append(orderIDs, orderId)
+		req.StartLocationID = startId
+	}
+	if endStr := query.Get("end_location_id"); endStr != "" {
+		endId, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil {
+			writeBadRequest(w, "invalid end_location_id")
+			return
+		}
+		req.EndLocationID = endId
+	}
+	if roundTripStr := query.Get("round_trip"); roundTripStr != "" {
+		roundTrip, err := strconv.ParseBool(roundTripStr)
+		if err != nil {
+			writeBadRequest(w, "invalid round_trip")
+			return
+		}
+		req.RoundTrip = roundTrip
+	}
+	if viaStr := query.Get("via_location_ids"); viaStr != "" {
+		viaIds, err := parseOrderIDs(viaStr)
+		if err != nil {
+			writeBadRequest(w, "invalid via_location_ids")
+			return
+		}
+		req.ViaLocationIDs = viaIds
 	}
 
-	// Get Orders data
-	orders, err := h.Service.GetOrdersByIDs(orderIDs)
-	if err != nil {
-		log.Printf("failed to fetch orders, err %+v", err)
-		http.Error(w, "failed to fetch orders", http.StatusInternalServerError)
+	if riderIdStr := query.Get("riderId"); riderIdStr != "" {
+		riderId, err := strconv.ParseInt(riderIdStr, 10, 64)
+		if err != nil {
+			writeBadRequest(w, "invalid riderId")
+			return
+		}
+		req.RiderID = riderId
+	} else {
+		if req.StartLocationID == 0 {
+			lat, err1 := strconv.ParseFloat(query.Get("lat"), 64)
+			lon, err2 := strconv.ParseFloat(query.Get("lon"), 64)
+
+			if err1 != nil || err2 != nil {
+				writeBadRequest(w, "invalid coordinates")
+				return
+			}
+
+			// User - Delivery partners current location
+			req.Start = orderModel.Location{
+				Latitude:  lat,
+				Longitude: lon,
+			}
+		}
+
+		orderIDs, err := parseOrderIDs(query.Get("orderIds"))
+		if err != nil {
+			writeBadRequest(w, fmt.Sprintf("invalid orderIds: %v", err))
+			return
+		}
+		req.OrderIDs = orderIDs
+	}
+
+	if nowStr := query.Get("now"); nowStr != "" {
+		now, err := time.Parse(time.RFC3339, nowStr)
+		if err != nil {
+			writeBadRequest(w, "invalid now, expected RFC3339 timestamp")
+			return
+		}
+		req.Now = now
+	}
+
+	// Rider's load limits, taken from the rider when riderId is given
+	if capacityStr := query.Get("capacity"); capacityStr != "" {
+		capacity, err := strconv.Atoi(capacityStr)
+		if err != nil {
+			writeBadRequest(w, "invalid capacity")
+			return
+		}
+		req.Capacity = capacity
+	}
+	// k - Number of runner-up routes to return besides the best one
+	if kStr := query.Get("k"); kStr != "" {
+		k, err := strconv.Atoi(kStr)
+		if err != nil {
+			writeBadRequest(w, "invalid k")
+			return
+		}
+		req.Alternatives = k
+	}
+	if softStr := query.Get("soft_windows"); softStr != "" {
+		soft, err := strconv.ParseBool(softStr)
+		if err != nil {
+			writeBadRequest(w, "invalid soft_windows")
+			return
+		}
+		req.SoftWindows = soft
+	}
+	if maxWeightStr := query.Get("max_weight_kg"); maxWeightStr != "" {
+		maxWeight, err := strconv.ParseFloat(maxWeightStr, 64)
+		if err != nil {
+			writeBadRequest(w, "invalid max_weight_kg")
+			return
+		}
+		req.MaxWeightKg = maxWeight
+	}
+
+	// format - json (default), geojson or polyline
+	format := query.Get("format")
+	switch format {
+	case "", utils.FormatJSON, utils.FormatGeoJSON, utils.FormatPolyline:
+	default:
+		writeBadRequest(w, "invalid format, expected json, geojson or polyline")
 		return
 	}
 
-	if len(orders) != 2 {
-		http.Error(w, "expected exactly 2 orders", http.StatusBadRequest)
+	// Returns the best possible route to cover all orders
+	bestRoute, err := h.RouteService.GetBestRoute(r.Context(), req)
+	if err != nil {
+		writeError(w, "failed to compute best route", err)
 		return
 	}
 
-	locIDs := []int64{
-		int64(orders[0].ResLocationID), int64(orders[0].CusLocationID),
-		int64(orders[1].ResLocationID), int64(orders[1].CusLocationID),
+	switch format {
+	case utils.FormatGeoJSON:
+		w.Header().Set("Content-Type", "application/geo+json")
+		json.NewEncoder(w).Encode(utils.RouteGeoJSON(*bestRoute))
+		return
+	case utils.FormatPolyline:
+		bestRoute.Polyline = utils.RoutePolyline(*bestRoute)
+		for i := range bestRoute.Alternatives {
+			bestRoute.Alternatives[i].Route.Polyline = utils.RoutePolyline(bestRoute.Alternatives[i].Route)
+		}
 	}
-	// Get locations data for the locationIds in the orders
-	locations, err := h.Service.GetLocationsByIDs(locIDs)
-	if err != nil {
-		http.Error(w, "failed to fetch locations", http.StatusInternalServerError)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bestRoute)
+}
+
+// Route stop types in an insertion request
+const (
+	stepPickup = "pickup"
+	stepDrop   = "drop"
+)
+
+/*
+* InsertionRequest - A rider's route in progress and the order we want to add.
+* Route is the full planned route, the first completed_steps of it are done.
+ */
+type InsertionRequest struct {
+	Lat              float64            `json:"lat"`
+	Lon              float64            `json:"lon"`
+	Route            []PlannedStopInput `json:"route"`
+	CompletedSteps   int                `json:"completed_steps"`
+	CommittedSteps   int                `json:"committed_steps"`
+	PickedUpOrderIDs []int64            `json:"picked_up_order_ids"`
+	CandidateOrderID int64              `json:"candidate_order_id"`
+	Vehicle          string             `json:"vehicle"`
+	Now              *time.Time         `json:"now"`
+	Capacity         int                `json:"capacity"`
+	MaxWeightKg      float64            `json:"max_weight_kg"`
+	Limit            int                `json:"limit"`
+}
+
+// PlannedStopInput - A stop of the planned route, step is pickup or drop
+type PlannedStopInput struct {
+	OrderID int64  `json:"order_id"`
+	Step    string `json:"step"`
+}
+
+/*
+* GetInsertions - Best positions to add a new order to a rider's route in progress,
+* with how much longer the route gets and how each customer's ETA moves.
+ */
+func (h *OrderHandler) GetInsertions(w http.ResponseWriter, r *http.Request) {
+	var body InsertionRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeBadRequest(w, "invalid request")
 		return
 	}
 
-	// User - Delivery partners current location
-	userLocation := orderModel.Location{
-		Latitude: lat, 
-		Longitude: lon,
+	req := orderService.InsertionRequest{
+		Start: orderModel.Location{
+			Latitude:  body.Lat,
+			Longitude: body.Lon,
+		},
+		Route:            make([]utils.PlannedStop, 0, len(body.Route)),
+		CompletedSteps:   body.CompletedSteps,
+		CommittedSteps:   body.CommittedSteps,
+		PickedUpOrderIDs: body.PickedUpOrderIDs,
+		CandidateOrderID: body.CandidateOrderID,
+		Vehicle:          body.Vehicle,
+		Capacity:         body.Capacity,
+		MaxWeightKg:      body.MaxWeightKg,
+		Limit:            body.Limit,
+	}
+	for _, stop := range body.Route {
+		if stop.Step != stepPickup && stop.Step != stepDrop {
+			writeBadRequest(w, fmt.Sprintf("invalid step %q, expected pickup or drop", stop.Step))
+			return
+		}
+		req.Route = append(req.Route, utils.PlannedStop{OrderID: stop.OrderID, IsPickup: stop.Step == stepPickup})
+	}
+	if body.Now != nil {
+		req.Now = *body.Now
 	}
 
-	// Returns the best possible route to cover all orders
-	bestRoute := utils.GetBestRoute(userLocation, orders, locations)
+	result, err := h.RouteService.RankInsertions(r.Context(), req)
+	if err != nil {
+		writeError(w, "failed to compute insertions", err)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(bestRoute)
+	json.NewEncoder(w).Encode(result)
 }
 
+// parseOrderIDs - Comma separated order ids, duplicates are dropped
+func parseOrderIDs(raw string) ([]int64, error) {
+	orderIDs := make([]int64, 0)
+	seenOrderIDs := make(map[int64]struct{})
+
+	for _, id := range strings.Split(raw, ",") {
+		orderId, err := strconv.ParseInt(strings.TrimSpace(id), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seenOrderIDs[orderId]; ok {
+			continue
+		}
+		seenOrderIDs[orderId] = struct{}{}
+		orderIDs = append(orderIDs, orderId)
+	}
+
+	return orderIDs, nil
+}
 
-func (h *OrderHandler) GetDiscontinuedVehicles(w http.ResponseWriter, r *http.Request){
+func (h *OrderHandler) GetDiscontinuedVehicles(w http.ResponseWriter, r *http.Request) {
 	vpicURL := "https://vpic.nhtsa.dot.gov/api/vehicles/getmodelsformakeyear/make/honda/modelyear/{startYear}?format=json"
 	currentYearString := r.URL.Query().Get("year")
 
 	currentYear, err := strconv.ParseInt(currentYearString, 10, 64)
 	if err != nil {
 		fmt.Println("Failed to convert year")
-		http.Error(w, "Failed to convert year", http.StatusBadRequest)
+		writeBadRequest(w, "Failed to convert year")
 		return
 	}
 
 	allModels := make(map[int64]orderModel.VehicleModel)
 	activeModels := make(map[int64]struct{})
 
-	// Optimizations 
+	// Optimizations
 	// Make parallel api calls to get the data
 	for y := currentYear; y > currentYear-10; y-- {
 		url := strings.ReplaceAll(vpicURL, "{startYear}", strconv.FormatInt(y, 10))
@@ -194,15 +563,13 @@ func (h *OrderHandler) GetDiscontinuedVehicles(w http.ResponseWriter, r *http.Re
 		})
 		cancel()
 		if reqErr != nil || status < 200 || status >= 300 {
-			log.Printf("vpic request failed for year %d, status=%d, err=%v", y, status, reqErr)
-			http.Error(w, "failed to fetch vehicle models", http.StatusInternalServerError)
+			writeError(w, "failed to fetch vehicle models", fmt.Errorf("vpic request for year %d, status=%d, err=%v", y, status, reqErr))
 			return
 		}
 
 		var apiResp orderModel.VPICResponse
 		if err := json.Unmarshal(respBytes, &apiResp); err != nil {
-			log.Printf("failed to parse vpic response for year %d: %v", y, err)
-			http.Error(w, "failed to parse vehicle models", http.StatusInternalServerError)
+			writeError(w, "failed to parse vehicle models", fmt.Errorf("vpic response for year %d: %w", y, err))
 			return
 		}
 
@@ -225,13 +592,13 @@ func (h *OrderHandler) GetDiscontinuedVehicles(w http.ResponseWriter, r *http.Re
 	json.NewEncoder(w).Encode(map[string]any{
 		"year":         currentYear,
 		"discontinued": discontinued,
-		"count": len(discontinued),
+		"count":        len(discontinued),
 	})
 }
 
-	// Use util method to make API calls for last 10 years
-    // 1. API call for 2025
-    // 2. Add all the models in the all models hashmap
-    // 3. For current and lastyear -> add in active models hashmap  
-    // 4. Loop though all models and check if present in active then remove from it
-    // 5. Return all models
+// Use util method to make API calls for last 10 years
+// 1. API call for 2025
+// 2. Add all the models in the all models hashmap
+// 3. For current and lastyear -> add in active models hashmap
+// 4. Loop though all models and check if present in active then remove from it
+// 5. Return all models
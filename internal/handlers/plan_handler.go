@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	orderModel "github.com/SHIVAMSINGH0101/go-demo/internal/models"
+	orderService "github.com/SHIVAMSINGH0101/go-demo/internal/services"
+	"github.com/gorilla/mux"
+)
+
+type PlanHandler struct {
+	RouteService orderService.RouteService
+}
+
+func NewPlanHandler(routeService orderService.RouteService) *PlanHandler {
+	return &PlanHandler{
+		RouteService: routeService,
+	}
+}
+
+func (h *PlanHandler) RegisterPlanHandlers(r *mux.Router) {
+	r.HandleFunc("/plan", h.PlanFleet).Methods("POST")
+}
+
+// PlanRequest - Orders to share out between riders
+type PlanRequest struct {
+	OrderIDs []int64            `json:"order_ids"`
+	Riders   []PlanRiderRequest `json:"riders"`
+	// Objective - Route objective of every rider, as on best route
+	Objective string `json:"objective"`
+	// FleetObjective - "sum" (default) or "max" of the riders' objective values
+	FleetObjective string     `json:"fleet_objective"`
+	Now            *time.Time `json:"now"`
+	SoftWindows    bool       `json:"soft_windows"`
+}
+
+// PlanRiderRequest - A stored rider by id, or any rider by position. Given fields win over the stored rider's
+type PlanRiderRequest struct {
+	RiderID     int64    `json:"rider_id"`
+	Lat         *float64 `json:"lat"`
+	Lon         *float64 `json:"lon"`
+	Vehicle     string   `json:"vehicle"`
+	Capacity    int      `json:"capacity"`
+	MaxWeightKg float64  `json:"max_weight_kg"`
+}
+
+/*
+* PlanFleet - Decides which rider takes which orders and the route of each.
+* Orders no rider can take within the constraints are listed as unassigned.
+ */
+func (h *PlanHandler) PlanFleet(w http.ResponseWriter, r *http.Request) {
+	var req PlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, "invalid request")
+		return
+	}
+
+	fleetReq := orderService.FleetRequest{
+		OrderIDs:       req.OrderIDs,
+		Objective:      req.Objective,
+		FleetObjective: req.FleetObjective,
+		SoftWindows:    req.SoftWindows,
+	}
+	if req.Now != nil {
+		fleetReq.Now = *req.Now
+	}
+	for _, rider := range req.Riders {
+		if (rider.Lat == nil) != (rider.Lon == nil) {
+			writeBadRequest(w, "rider lat and lon must be given together")
+			return
+		}
+		fleetRider := orderService.FleetRiderRequest{
+			RiderID:     rider.RiderID,
+			Vehicle:     rider.Vehicle,
+			Capacity:    rider.Capacity,
+			MaxWeightKg: rider.MaxWeightKg,
+		}
+		if rider.Lat != nil {
+			fleetRider.Start = &orderModel.Location{Latitude: *rider.Lat, Longitude: *rider.Lon}
+		}
+		fleetReq.Riders = append(fleetReq.Riders, fleetRider)
+	}
+
+	plan, err := h.RouteService.PlanFleet(r.Context(), fleetReq)
+	if err != nil {
+		writeError(w, "failed to plan fleet", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	orderModel "github.com/SHIVAMSINGH0101/go-demo/internal/models"
+	orderService "github.com/SHIVAMSINGH0101/go-demo/internal/services"
+	"github.com/gorilla/mux"
+)
+
+type RestaurantHandler struct {
+	Service orderService.OrderService
+}
+
+func NewRestaurantHandler(service orderService.OrderService) *RestaurantHandler {
+	return &RestaurantHandler{
+		Service: service,
+	}
+}
+
+func (h *RestaurantHandler) RegisterRestaurantHandlers(r *mux.Router) {
+	r.HandleFunc("/restaurant/create", h.CreateRestaurant).Methods("POST")
+	r.HandleFunc("/restaurant/{id}", h.GetRestaurant).Methods("GET")
+}
+
+// CreateRestaurantRequest - Restaurant orders are picked up from
+type CreateRestaurantRequest struct {
+	Name string  `json:"name"`
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+}
+
+func (h *RestaurantHandler) CreateRestaurant(w http.ResponseWriter, r *http.Request) {
+	var req CreateRestaurantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, "invalid request")
+		return
+	}
+
+	restaurant := &orderModel.Restaurant{
+		Name: req.Name,
+		Location: orderModel.Location{
+			Latitude:  req.Lat,
+			Longitude: req.Lon,
+		},
+	}
+	restaurantId, err := h.Service.CreateRestaurant(r.Context(), restaurant)
+	if err != nil {
+		writeError(w, "failed to create restaurant", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":       "created",
+		"restaurantId": restaurantId,
+		"locationId":   restaurant.LocationID,
+	})
+}
+
+func (h *RestaurantHandler) GetRestaurant(w http.ResponseWriter, r *http.Request) {
+	restaurantId, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeBadRequest(w, "invalid restaurant id")
+		return
+	}
+
+	restaurant, err := h.Service.GetRestaurantByID(r.Context(), restaurantId)
+	if err != nil {
+		writeError(w, "failed to fetch restaurant", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(restaurant)
+}
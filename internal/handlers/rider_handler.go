@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	orderModel "github.com/SHIVAMSINGH0101/go-demo/internal/models"
+	orderService "github.com/SHIVAMSINGH0101/go-demo/internal/services"
+	"github.com/gorilla/mux"
+)
+
+type RiderHandler struct {
+	Service orderService.RiderService
+	Replan  orderService.ReplanService
+}
+
+func NewRiderHandler(service orderService.RiderService, replan orderService.ReplanService) *RiderHandler {
+	return &RiderHandler{
+		Service: service,
+		Replan:  replan,
+	}
+}
+
+func (h *RiderHandler) RegisterRiderHandlers(r *mux.Router) {
+	r.HandleFunc("/rider/create", h.CreateRider).Methods("POST")
+	r.HandleFunc("/rider/{id}", h.GetRider).Methods("GET")
+	r.HandleFunc("/rider/{id}/shift", h.UpdateShiftStatus).Methods("POST")
+	r.HandleFunc("/rider/{id}/location", h.RecordLocation).Methods("POST")
+	r.HandleFunc("/rider/{id}/orders", h.AssignOrders).Methods("POST")
+	r.HandleFunc("/rider/{id}/orders", h.GetActiveOrders).Methods("GET")
+	r.HandleFunc("/rider/{id}/orders/{orderId}", h.UnassignOrder).Methods("DELETE")
+	r.HandleFunc("/rider/{id}/plan", h.GetCurrentPlan).Methods("GET")
+	r.HandleFunc("/rider/{id}/plan", h.ReplanRoute).Methods("POST")
+	r.HandleFunc("/rider/{id}/plan/history", h.GetPlanHistory).Methods("GET")
+}
+
+// CreateRiderRequest - Rider profile, capacity is the bag units carried at once
+type CreateRiderRequest struct {
+	Name        string  `json:"name"`
+	VehicleType string  `json:"vehicle_type"`
+	Capacity    int     `json:"capacity"`
+	MaxWeightKg float64 `json:"max_weight_kg"`
+}
+
+func (h *RiderHandler) CreateRider(w http.ResponseWriter, r *http.Request) {
+	var req CreateRiderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, "invalid request")
+		return
+	}
+
+	riderId, err := h.Service.CreateRider(r.Context(), &orderModel.Rider{
+		Name:        req.Name,
+		VehicleType: req.VehicleType,
+		Capacity:    req.Capacity,
+		MaxWeightKg: req.MaxWeightKg,
+	})
+	if err != nil {
+		writeError(w, "failed to create rider", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "created",
+		"riderId": riderId,
+	})
+}
+
+func (h *RiderHandler) GetRider(w http.ResponseWriter, r *http.Request) {
+	riderId, ok := riderIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	rider, err := h.Service.GetRiderByID(r.Context(), riderId)
+	if err != nil {
+		writeError(w, "failed to fetch rider", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rider)
+}
+
+// UpdateShiftStatusRequest - ON_SHIFT, OFF_SHIFT or ON_BREAK
+type UpdateShiftStatusRequest struct {
+	Status orderModel.RiderShiftStatus `json:"status"`
+}
+
+func (h *RiderHandler) UpdateShiftStatus(w http.ResponseWriter, r *http.Request) {
+	riderId, ok := riderIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateShiftStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, "invalid request")
+		return
+	}
+
+	if err := h.Service.UpdateShiftStatus(r.Context(), riderId, req.Status); err != nil {
+		writeError(w, "failed to update shift status", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RecordLocationRequest - GPS ping, recorded_at defaults to the time it is received
+type RecordLocationRequest struct {
+	Lat        float64    `json:"lat"`
+	Lon        float64    `json:"lon"`
+	RecordedAt *time.Time `json:"recorded_at"`
+}
+
+func (h *RiderHandler) RecordLocation(w http.ResponseWriter, r *http.Request) {
+	riderId, ok := riderIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req RecordLocationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, "invalid request")
+		return
+	}
+
+	loc := &orderModel.RiderLocation{
+		RiderID:   riderId,
+		Latitude:  req.Lat,
+		Longitude: req.Lon,
+	}
+	if req.RecordedAt != nil {
+		loc.RecordedAt = *req.RecordedAt
+	}
+
+	if err := h.Service.RecordLocation(r.Context(), loc); err != nil {
+		writeError(w, "failed to record location", err)
+		return
+	}
+
+	// Checked against the rider's plan in the background, the ping is answered once stored
+	h.Replan.QueuePosition(loc)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AssignOrdersRequest - Orders to hand to the rider
+type AssignOrdersRequest struct {
+	OrderIDs []int64 `json:"order_ids"`
+}
+
+func (h *RiderHandler) AssignOrders(w http.ResponseWriter, r *http.Request) {
+	riderId, ok := riderIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req AssignOrdersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.OrderIDs) == 0 {
+		writeBadRequest(w, "invalid request")
+		return
+	}
+
+	if err := h.Service.AssignOrders(r.Context(), riderId, req.OrderIDs); err != nil {
+		writeError(w, "failed to assign orders", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetActiveOrders - Assigned orders that are not delivered or cancelled yet
+func (h *RiderHandler) GetActiveOrders(w http.ResponseWriter, r *http.Request) {
+	riderId, ok := riderIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	orderIds, err := h.Service.GetActiveOrderIDs(r.Context(), riderId)
+	if err != nil {
+		writeError(w, "failed to fetch rider orders", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"riderId":  riderId,
+		"orderIds": orderIds,
+	})
+}
+
+func (h *RiderHandler) UnassignOrder(w http.ResponseWriter, r *http.Request) {
+	riderId, ok := riderIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	orderId, err := strconv.ParseInt(mux.Vars(r)["orderId"], 10, 64)
+	if err != nil {
+		writeBadRequest(w, "invalid order id")
+		return
+	}
+
+	if err := h.Service.UnassignOrder(r.Context(), riderId, orderId); err != nil {
+		writeError(w, "failed to unassign order", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetCurrentPlan - The latest version of the rider's plan
+func (h *RiderHandler) GetCurrentPlan(w http.ResponseWriter, r *http.Request) {
+	riderId, ok := riderIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	plan, err := h.Replan.GetCurrentPlan(r.Context(), riderId)
+	if err != nil {
+		writeError(w, "failed to fetch route plan", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
+
+// GetPlanHistory - Every version of the rider's plan, newest first
+func (h *RiderHandler) GetPlanHistory(w http.ResponseWriter, r *http.Request) {
+	riderId, ok := riderIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	plans, err := h.Replan.GetPlanHistory(r.Context(), riderId)
+	if err != nil {
+		writeError(w, "failed to fetch route plans", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"riderId": riderId,
+		"plans":   plans,
+	})
+}
+
+// ReplanRoute - Re-plans the rider from their latest position on demand
+func (h *RiderHandler) ReplanRoute(w http.ResponseWriter, r *http.Request) {
+	riderId, ok := riderIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	plan, err := h.Replan.Replan(r.Context(), riderId, orderModel.ReplanManual)
+	if err != nil {
+		writeError(w, "failed to re-plan route", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(plan)
+}
+
+func riderIDFromPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	riderId, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeBadRequest(w, "invalid rider id")
+		return 0, false
+	}
+	return riderId, true
+}
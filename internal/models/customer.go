@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Customer - Who orders are delivered to, with their delivery address
+type Customer struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	Phone      string    `json:"phone,omitempty"`
+	LocationID int64     `json:"locationId"`
+	Location   Location  `json:"location"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
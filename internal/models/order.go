@@ -2,36 +2,93 @@ package models
 
 import "time"
 
-// Location - Stores location coordinates for actors - Restaurant and Customer
+// Location - Stores location coordinates for actors - Restaurant, Customer and Hub
 type Location struct {
-	ID        int     `json:"id"`
-	Name      string  `json:"name"`
-	Latitude  float64 `json:"latitude"`
-	Longitude float64 `json:"longitude"`
+	ID        int          `json:"id"`
+	Name      string       `json:"name"`
+	Latitude  float64      `json:"latitude"`
+	Longitude float64      `json:"longitude"`
+	Type      LocationType `json:"type,omitempty"`
 }
 
+// LocationType - What a location is, hubs are where fleets start or end their shift
+type LocationType string
+
+const (
+	LocationTypeAddress    LocationType = "ADDRESS"
+	LocationTypeRestaurant LocationType = "RESTAURANT"
+	LocationTypeCustomer   LocationType = "CUSTOMER"
+	LocationTypeHub        LocationType = "HUB"
+)
+
 // Order - Stores customer's order info - restaurant and customer locationId
 type Order struct {
-	OrderID int `json:"orderId"`
+	OrderID       int   `json:"orderId"`
 	ResLocationID int64 `json:"resLocationId"`
 	CusLocationID int64 `json:"cusLocationId"`
-	PrepTimeInMinutes float64 `json:"prepTimeInMinutes"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	// RestaurantID, CustomerID - Stored restaurant and customer of the order, nil when it was placed with coordinates
+	RestaurantID      *int64      `json:"restaurantId,omitempty"`
+	CustomerID        *int64      `json:"customerId,omitempty"`
+	PrepTimeInMinutes float64     `json:"prepTimeInMinutes"`
+	Status            OrderStatus `json:"status"`
+	// PromisedBy - Delivery deadline promised to the customer, nil when there is none
+	PromisedBy *time.Time `json:"promisedBy,omitempty"`
+	// SLAWeight - How much a minute of lateness on this order counts, 1 by default
+	SLAWeight float64 `json:"slaWeight"`
+	// Size - Bag units the order takes, 1 by default
+	Size     int     `json:"size"`
+	WeightKg float64 `json:"weightKg"`
+	// MaxInBagMinutes - Longest the food may be carried before it is dropped, 0 for no limit
+	MaxInBagMinutes float64 `json:"maxInBagMinutes"`
+	// DeliverAfter, DeliverBefore - Delivery window of a scheduled order, nil for ASAP
+	DeliverAfter  *time.Time `json:"deliverAfter,omitempty"`
+	DeliverBefore *time.Time `json:"deliverBefore,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+// OrderDetails - An order with its restaurant and customer locations
+type OrderDetails struct {
+	Order
+	Restaurant Location `json:"restaurant"`
+	Customer   Location `json:"customer"`
+}
+
+// OrderStatus - Lifecycle state of an order
+type OrderStatus string
+
+const (
+	OrderStatusCreated   OrderStatus = "CREATED"
+	OrderStatusAccepted  OrderStatus = "ACCEPTED"
+	OrderStatusPreparing OrderStatus = "PREPARING"
+	OrderStatusReady     OrderStatus = "READY"
+	OrderStatusPickedUp  OrderStatus = "PICKED_UP"
+	OrderStatusDelivered OrderStatus = "DELIVERED"
+	OrderStatusCancelled OrderStatus = "CANCELLED"
+)
+
+// OrderStatusChange - One row of an order's status history
+type OrderStatusChange struct {
+	ID      int64 `json:"id"`
+	OrderID int   `json:"orderId"`
+	// FromStatus - Empty on the row that records the order's creation
+	FromStatus OrderStatus `json:"fromStatus,omitempty"`
+	ToStatus   OrderStatus `json:"toStatus"`
+	ChangedAt  time.Time   `json:"changedAt"`
 }
 
 type VehicleModel struct {
-	MakeId int64 `json:"Make_ID"`
-	MakeName string `json:"Make_Name"`
-	ModelId int64 `json:"Model_ID"`
+	MakeId    int64  `json:"Make_ID"`
+	MakeName  string `json:"Make_Name"`
+	ModelId   int64  `json:"Model_ID"`
 	ModelName string `json:"Model_Name"`
 }
 
-// VeichlesSold 
+// VeichlesSold
 
 type VPICResponse struct {
-	Count int `json:"Count"`
-	Message string `json:"Message"`
-	SearchCriteria string `json:"SearchCriteria"`
-	Results []VehicleModel `json:"Results"`
+	Count          int            `json:"Count"`
+	Message        string         `json:"Message"`
+	SearchCriteria string         `json:"SearchCriteria"`
+	Results        []VehicleModel `json:"Results"`
 }
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// OrderSort - Order in which a listing returns orders, a leading - sorts descending
+type OrderSort string
+
+const (
+	OrderSortIDAsc         OrderSort = "id"
+	OrderSortIDDesc        OrderSort = "-id"
+	OrderSortCreatedAtAsc  OrderSort = "created_at"
+	OrderSortCreatedAtDesc OrderSort = "-created_at"
+)
+
+// BoundingBox - Area between two corners, both edges included
+type BoundingBox struct {
+	MinLatitude  float64
+	MinLongitude float64
+	MaxLatitude  float64
+	MaxLongitude float64
+}
+
+// OrderCursor - Last order of the previous page, the listing continues after it
+type OrderCursor struct {
+	OrderID   int64     `json:"orderId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+/*
+* OrderFilter - Which orders a listing returns. Zero fields don't filter,
+* CreatedFrom is inclusive and CreatedTo exclusive. CustomerBox is matched
+* against the customer location of the order.
+ */
+type OrderFilter struct {
+	CreatedFrom          *time.Time
+	CreatedTo            *time.Time
+	Statuses             []OrderStatus
+	RestaurantID         *int64
+	RestaurantLocationID *int64
+	CustomerBox          *BoundingBox
+	Sort                 OrderSort
+	After                *OrderCursor
+	Limit                int
+}
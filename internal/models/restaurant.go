@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Restaurant - Where orders are picked up, orders placed for it reuse its location
+type Restaurant struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	LocationID int64     `json:"locationId"`
+	Location   Location  `json:"location"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Rider - Delivery partner with their vehicle and last known position
+type Rider struct {
+	ID          int64            `json:"id"`
+	Name        string           `json:"name"`
+	VehicleType string           `json:"vehicleType"`
+	Capacity    int              `json:"capacity"`
+	MaxWeightKg float64          `json:"maxWeightKg"`
+	ShiftStatus RiderShiftStatus `json:"shiftStatus"`
+	// LastLocationAt is nil until the first GPS ping arrives
+	LastLatitude   float64    `json:"lastLatitude"`
+	LastLongitude  float64    `json:"lastLongitude"`
+	LastLocationAt *time.Time `json:"lastLocationAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+// RiderShiftStatus - Whether the rider is working right now
+type RiderShiftStatus string
+
+const (
+	RiderShiftOff     RiderShiftStatus = "OFF_SHIFT"
+	RiderShiftOn      RiderShiftStatus = "ON_SHIFT"
+	RiderShiftOnBreak RiderShiftStatus = "ON_BREAK"
+)
+
+// RiderLocation - A single GPS ping from a rider
+type RiderLocation struct {
+	RiderID    int64     `json:"riderId"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	RecordedAt time.Time `json:"recordedAt"`
+}
+
+// RiderAssignment - An order handed to a rider
+type RiderAssignment struct {
+	RiderID    int64     `json:"riderId"`
+	OrderID    int64     `json:"orderId"`
+	AssignedAt time.Time `json:"assignedAt"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// RoutePlanVersion - A rider's planned route, kept until a re-plan adds the next version
+type RoutePlanVersion struct {
+	ID      int64        `json:"id"`
+	RiderID int64        `json:"riderId"`
+	Version int          `json:"version"`
+	Reason  ReplanReason `json:"reason"`
+	// PlannedAt is when the route starts, step ETAs count from it
+	PlannedAt        time.Time          `json:"plannedAt"`
+	StartLatitude    float64            `json:"startLatitude"`
+	StartLongitude   float64            `json:"startLongitude"`
+	TotalTimeMinutes float64            `json:"totalTimeMinutes"`
+	Steps            []PlannedRouteStep `json:"steps"`
+	CreatedAt        time.Time          `json:"createdAt"`
+}
+
+// PlannedRouteStep - A stop of a stored plan with where it is and when it should be done
+type PlannedRouteStep struct {
+	StopType   string    `json:"stopType"`
+	OrderID    int       `json:"orderId"`
+	LocationID int       `json:"locationId"`
+	Name       string    `json:"name"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	ETA        time.Time `json:"eta"`
+}
+
+// ReplanReason - Why a plan version was made
+type ReplanReason string
+
+const (
+	ReplanInitial       ReplanReason = "initial"
+	ReplanOffRoute      ReplanReason = "off_route"
+	ReplanDelay         ReplanReason = "delay"
+	ReplanOrdersChanged ReplanReason = "orders_changed"
+	ReplanManual        ReplanReason = "manual"
+)
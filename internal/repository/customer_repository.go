@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/apperrors"
+	routeModels "github.com/SHIVAMSINGH0101/go-demo/internal/models"
+)
+
+// Customer repository interacts with customers and their rows in locations
+type CustomerRepository interface {
+	InsertCustomer(ctx context.Context, customer *routeModels.Customer) (int64, error)
+	GetCustomerByID(ctx context.Context, id int64) (*routeModels.Customer, error)
+}
+
+// ErrCustomerNotFound - No customer with the given id
+var ErrCustomerNotFound = apperrors.New(apperrors.ErrNotFound, "customer not found")
+
+type customerRepository struct {
+	db        DBTX
+	deadlines Deadlines
+	dialect   dialect
+}
+
+// InsertCustomer - Stores the customer, reusing the location when the address is already stored
+func (r *customerRepository) InsertCustomer(ctx context.Context, customer *routeModels.Customer) (int64, error) {
+	ctx, cancel := r.deadlines.tx(ctx)
+	defer cancel()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	customer.Location.Name = customer.Name
+	customer.Location.Type = routeModels.LocationTypeCustomer
+	locationId, err := r.dialect.upsertLocationID(ctx, tx, &customer.Location)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `INSERT INTO customers
+			(name, phone, locationId)
+			VALUES (?, ?, ?)`
+	result, err := tx.ExecContext(ctx, query, customer.Name, customer.Phone, locationId)
+	if err != nil {
+		return 0, insertError("customer", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	customer.ID = id
+	customer.LocationID = locationId
+	customer.Location.ID = int(locationId)
+	return id, nil
+}
+
+func (r *customerRepository) GetCustomerByID(ctx context.Context, id int64) (*routeModels.Customer, error) {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
+	query := `SELECT c.id, c.name, c.phone, c.locationId, c.createdAt, l.id, l.name, l.latitude, l.longitude, l.type
+			FROM customers c
+			JOIN locations l ON l.id = c.locationId
+			WHERE c.id = ?`
+
+	var customer routeModels.Customer
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&customer.ID, &customer.Name, &customer.Phone, &customer.LocationID,
+		&customer.CreatedAt, &customer.Location.ID, &customer.Location.Name, &customer.Location.Latitude,
+		&customer.Location.Longitude, &customer.Location.Type)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCustomerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &customer, nil
+}
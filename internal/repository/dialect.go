@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/database"
+	routeModels "github.com/SHIVAMSINGH0101/go-demo/internal/models"
+)
+
+/*
+* dialect - The statements MySQL and SQLite spell differently, every other
+* query is shared. MySQL upserts report the stored row's id through
+* LAST_INSERT_ID(id), SQLite ones through RETURNING id.
+ */
+type dialect struct {
+	upsertReturnsID  bool
+	upsertLocation   string
+	upsertRestaurant string
+	// keepAssigned - Appended to the assignment insert, leaves an order that is already assigned as it is
+	keepAssigned string
+	// claimOrder - Assigns the order unless some rider has it already
+	claimOrder string
+	// lockRider - Locks the rider row until the transaction ends
+	lockRider string
+}
+
+var mysqlDialect = dialect{
+	upsertLocation: `INSERT INTO locations
+			(name, latitude, longitude, type)
+			VALUES (?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)`,
+	upsertRestaurant: `INSERT INTO restaurants
+			(name, locationId)
+			VALUES (?, ?)
+			ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)`,
+	keepAssigned: `ON DUPLICATE KEY UPDATE orderId = orderId`,
+	claimOrder: `INSERT IGNORE INTO rider_order_assignments
+			(riderId, orderId, assignedAt)
+			VALUES (?, ?, ?)`,
+	lockRider: `SELECT id FROM riders WHERE id = ? FOR UPDATE`,
+}
+
+// sqliteDialect - SQLite has a single writer, so a transaction that writes holds every row it reads
+var sqliteDialect = dialect{
+	upsertReturnsID: true,
+	upsertLocation: `INSERT INTO locations
+			(name, latitude, longitude, type)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT (name, latitude, longitude) DO UPDATE SET name = excluded.name
+			RETURNING id`,
+	upsertRestaurant: `INSERT INTO restaurants
+			(name, locationId)
+			VALUES (?, ?)
+			ON CONFLICT (locationId) DO UPDATE SET locationId = excluded.locationId
+			RETURNING id`,
+	keepAssigned: `ON CONFLICT (orderId) DO NOTHING`,
+	claimOrder: `INSERT INTO rider_order_assignments
+			(riderId, orderId, assignedAt)
+			VALUES (?, ?, ?)
+			ON CONFLICT (orderId) DO NOTHING`,
+	lockRider: `SELECT id FROM riders WHERE id = ?`,
+}
+
+func dialectFor(driver string) (dialect, error) {
+	switch driver {
+	case database.DriverMySQL:
+		return mysqlDialect, nil
+	case database.DriverSQLite:
+		return sqliteDialect, nil
+	default:
+		return dialect{}, fmt.Errorf("unknown database driver %q", driver)
+	}
+}
+
+// upsert - Runs one of the dialect's upserts and returns the id of the inserted or stored row
+func (d dialect) upsert(ctx context.Context, db DBTX, query string, args ...interface{}) (int64, error) {
+	if d.upsertReturnsID {
+		var id int64
+		err := db.QueryRowContext(ctx, query, args...).Scan(&id)
+		return id, err
+	}
+
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+/*
+* upsertLocationID - Stores loc unless the same name and coordinates are stored.
+* A stored location of another type than loc's is ErrLocationTypeConflict, so a
+* restaurant is never reused as a customer or hub. Without a type it is stored
+* as an address and any stored type is reused.
+ */
+func (d dialect) upsertLocationID(ctx context.Context, db DBTX, loc *routeModels.Location) (int64, error) {
+	locationType := loc.Type
+	if locationType == "" {
+		locationType = routeModels.LocationTypeAddress
+	}
+
+	id, err := d.upsert(ctx, db, d.upsertLocation, loc.Name, loc.Latitude, loc.Longitude, locationType)
+	if err != nil {
+		return 0, insertError("location", err)
+	}
+	if loc.Type == "" {
+		return id, nil
+	}
+
+	var stored routeModels.LocationType
+	if err := db.QueryRowContext(ctx, `SELECT type FROM locations WHERE id = ?`, id).Scan(&stored); err != nil {
+		return 0, err
+	}
+	if stored != loc.Type {
+		return 0, fmt.Errorf("%w: %q is a %s location, not %s", ErrLocationTypeConflict, loc.Name, stored, loc.Type)
+	}
+	return id, nil
+}
+
+/*
+* NewRepositories - Every repository and the unit of work for a database
+* opened with driver, mysql or sqlite. It is the only way to build them,
+* so no repository runs SQL written for another driver.
+ */
+func NewRepositories(db *sql.DB, driver string, deadlines Deadlines) (Repositories, UnitOfWork, error) {
+	d, err := dialectFor(driver)
+	if err != nil {
+		return Repositories{}, nil, err
+	}
+	uow := &unitOfWork{db: db, deadlines: deadlines, dialect: d}
+	return bindRepositories(db, deadlines, d), uow, nil
+}
+
+func bindRepositories(db DBTX, deadlines Deadlines, d dialect) Repositories {
+	return Repositories{
+		Orders:      &orderRepository{db: db, deadlines: deadlines, dialect: d},
+		Restaurants: &restaurantRepository{db: db, deadlines: deadlines, dialect: d},
+		Customers:   &customerRepository{db: db, deadlines: deadlines, dialect: d},
+		Riders:      &riderRepository{db: db, deadlines: deadlines, dialect: d},
+		RoutePlans:  &routePlanRepository{db: db, deadlines: deadlines, dialect: d},
+	}
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/apperrors"
+	"github.com/go-sql-driver/mysql"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// MySQL error numbers callers can act on
+const (
+	mysqlDuplicateEntry   = 1062
+	mysqlMissingReference = 1452
+)
+
+/*
+* insertError - Turns a failed insert of entity into a domain error. Duplicate
+* keys are conflicts and missing foreign keys fail validation, the driver
+* error stays in the chain for logs either way.
+ */
+func insertError(entity string, err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case mysqlDuplicateEntry:
+			return apperrors.Wrap(apperrors.ErrConflict, entity+" already exists", err)
+		case mysqlMissingReference:
+			return apperrors.Wrap(apperrors.ErrValidation, entity+" refers to a missing row", err)
+		}
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			return apperrors.Wrap(apperrors.ErrConflict, entity+" already exists", err)
+		case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+			return apperrors.Wrap(apperrors.ErrValidation, entity+" refers to a missing row", err)
+		}
+	}
+	return fmt.Errorf("failed to insert %s: %w", entity, err)
+}
@@ -1,163 +1,530 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"strings"
 
+	"github.com/SHIVAMSINGH0101/go-demo/internal/apperrors"
 	routeModels "github.com/SHIVAMSINGH0101/go-demo/internal/models"
 )
 
 // Order repository interacts with orders and locations table
 type OrderRepository interface {
-	InsertLocation(loc *routeModels.Location) (int64, error)
-	GetLocationByID(id int64) (*routeModels.Location, error)
-	GetLocationsByIDs(ids []int64) ([]routeModels.Location, error)
+	InsertLocation(ctx context.Context, loc *routeModels.Location) (int64, error)
+	FindOrCreateLocation(ctx context.Context, loc *routeModels.Location) (int64, error)
+	GetLocationByID(ctx context.Context, id int64) (*routeModels.Location, error)
+	GetLocationsByIDs(ctx context.Context, ids []int64) ([]routeModels.Location, error)
+	GetLocationsByType(ctx context.Context, locationType routeModels.LocationType) ([]routeModels.Location, error)
 
-	InsertOrder(order *routeModels.Order) (int64, error)
-	GetOrderByID(id int64) (*routeModels.Order, error)
-	GetOrdersByIDs(ids []int64) ([]routeModels.Order, error)
+	InsertOrder(ctx context.Context, order *routeModels.Order) (int64, error)
+	GetOrderByID(ctx context.Context, id int64) (*routeModels.Order, error)
+	GetOrdersByIDs(ctx context.Context, ids []int64) ([]routeModels.Order, error)
+	GetUnassignedOrders(ctx context.Context, limit int) ([]routeModels.Order, error)
+	ListOrders(ctx context.Context, filter routeModels.OrderFilter) ([]routeModels.Order, error)
+
+	UpdateOrderStatus(ctx context.Context, orderId int64, from, to routeModels.OrderStatus) error
+	InsertOrderStatusChange(ctx context.Context, orderId int64, from, to routeModels.OrderStatus) error
+	GetOrderStatusHistory(ctx context.Context, orderId int64) ([]routeModels.OrderStatusChange, error)
 }
 
+var (
+	// ErrOrderNotFound - No order with the given id
+	ErrOrderNotFound = apperrors.New(apperrors.ErrNotFound, "order not found")
+	// ErrLocationNotFound - No location with the given id
+	ErrLocationNotFound = apperrors.New(apperrors.ErrNotFound, "location not found")
+	// ErrLocationTypeConflict - The same name and coordinates are stored as another type of location
+	ErrLocationTypeConflict = apperrors.New(apperrors.ErrConflict, "location is stored with another type")
+	// ErrOrderStatusConflict - The order left the expected status before the update landed
+	ErrOrderStatusConflict = apperrors.New(apperrors.ErrConflict, "order status changed concurrently")
+)
+
 type orderRepository struct {
-	db *sql.DB
+	db        DBTX
+	deadlines Deadlines
+	dialect   dialect
 }
 
-func NewOrderRepository(db *sql.DB) OrderRepository{
-	return &orderRepository{
-		db: db,
-	}
-}
+// CRUD operations on Location and Order
+func (r *orderRepository) InsertLocation(ctx context.Context, loc *routeModels.Location) (int64, error) {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
 
-// CRUD operations on Location and Order 
-func (r *orderRepository) InsertLocation(loc *routeModels.Location) (int64, error) {
 	query := `INSERT INTO locations 
-			(name, latitude, longitude)
-			VALUES (?, ?, ?)`
+			(name, latitude, longitude, type)
+			VALUES (?, ?, ?, ?)`
 
-	result, err := r.db.Exec(query, loc.Name, loc.Latitude, loc.Longitude)
+	locationType := loc.Type
+	if locationType == "" {
+		locationType = routeModels.LocationTypeAddress
+	}
+
+	result, err := r.db.ExecContext(ctx, query, loc.Name, loc.Latitude, loc.Longitude, locationType)
 	if err != nil {
-		return 0, errors.New("failed to insert location")
+		return 0, insertError("location", err)
 	}
 
 	return result.LastInsertId()
 }
 
-func (r *orderRepository) GetLocationByID(id int64) (*routeModels.Location, error) {
-	query := `SELECT id, name, latitude, longitude 
+/*
+* FindOrCreateLocation - Returns the id of the location with the same name and
+* coordinates, inserting it first when there is none. A stored location of
+* another type than loc's is a conflict, without a type any one is reused.
+ */
+func (r *orderRepository) FindOrCreateLocation(ctx context.Context, loc *routeModels.Location) (int64, error) {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
+	return r.dialect.upsertLocationID(ctx, r.db, loc)
+}
+
+func (r *orderRepository) GetLocationByID(ctx context.Context, id int64) (*routeModels.Location, error) {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
+	query := `SELECT id, name, latitude, longitude, type 
 			  FROM locations
 			  WHERE id = ?`
 
 	var loc routeModels.Location
-	err := r.db.QueryRow(query, id).Scan(&loc.ID, &loc.Name, &loc.Latitude, &loc.Longitude)
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&loc.ID, &loc.Name, &loc.Latitude, &loc.Longitude, &loc.Type)
 	if err != nil {
-		return nil, errors.New("location not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %d", ErrLocationNotFound, id)
+		}
+		return nil, err
 	}
 
 	return &loc, nil
 }
 
-func (r *orderRepository) GetLocationsByIDs(locationIds []int64) ([]routeModels.Location, error) {
+func (r *orderRepository) GetLocationsByIDs(ctx context.Context, locationIds []int64) ([]routeModels.Location, error) {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
 	if len(locationIds) == 0 {
-        return nil, nil
-    }
+		return nil, nil
+	}
 
-	placeHolder := strings.Repeat("?,", len(locationIds) - 1) + "?"
-	query := `SELECT id, name, latitude, longitude 
+	placeHolder := strings.Repeat("?,", len(locationIds)-1) + "?"
+	query := `SELECT id, name, latitude, longitude, type 
 				FROM locations
 				WHERE id IN (` + placeHolder + `)`
-	
+
 	args := make([]interface{}, len(locationIds))
-    for i, id := range locationIds {
-        args[i] = id
-    }
-
-    rows, err := r.db.Query(query, args...)
-    if err != nil {
-        return nil, err
-    }
-    defer rows.Close()
-
-    var locs []routeModels.Location
-    for rows.Next() {
-        var loc routeModels.Location
-        if err := rows.Scan(&loc.ID, &loc.Name, &loc.Latitude, &loc.Longitude); err != nil {
-            return nil, err
-        }
-        locs = append(locs, loc)
-    }
-    if err := rows.Err(); err != nil {
-        return nil, err
-    }
-
-    return locs, nil
-}
-
-func (r *orderRepository) InsertOrder(order *routeModels.Order) (int64, error) {
+	for i, id := range locationIds {
+		args[i] = id
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var locs []routeModels.Location
+	for rows.Next() {
+		var loc routeModels.Location
+		if err := rows.Scan(&loc.ID, &loc.Name, &loc.Latitude, &loc.Longitude, &loc.Type); err != nil {
+			return nil, err
+		}
+		locs = append(locs, loc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return locs, nil
+}
+
+// GetLocationsByType - Every location of a type, e.g. all hubs
+func (r *orderRepository) GetLocationsByType(ctx context.Context, locationType routeModels.LocationType) ([]routeModels.Location, error) {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
+	query := `SELECT id, name, latitude, longitude, type 
+				FROM locations
+				WHERE type = ?
+				ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, locationType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locs := make([]routeModels.Location, 0)
+	for rows.Next() {
+		var loc routeModels.Location
+		if err := rows.Scan(&loc.ID, &loc.Name, &loc.Latitude, &loc.Longitude, &loc.Type); err != nil {
+			return nil, err
+		}
+		locs = append(locs, loc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return locs, nil
+}
+
+func (r *orderRepository) InsertOrder(ctx context.Context, order *routeModels.Order) (int64, error) {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
 	query := `INSERT INTO orders 
-		(resLocationId, cusLocationId, prepTimeInMinutes)
-		VALUES (?, ?, ?)`
+		(resLocationId, cusLocationId, restaurantId, customerId, prepTimeInMinutes, promisedBy, slaWeight, size,
+		weightKg, maxInBagMinutes, deliverAfter, deliverBefore)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	weight := order.SLAWeight
+	if weight <= 0 {
+		weight = 1
+	}
+	size := order.Size
+	if size <= 0 {
+		size = 1
+	}
 
-	result, err := r.db.Exec(query, order.ResLocationID, order.CusLocationID, order.PrepTimeInMinutes)
+	result, err := r.db.ExecContext(ctx, query, order.ResLocationID, order.CusLocationID, order.RestaurantID, order.CustomerID,
+		order.PrepTimeInMinutes, order.PromisedBy, weight, size, order.WeightKg, order.MaxInBagMinutes,
+		order.DeliverAfter, order.DeliverBefore)
 	if err != nil {
-		return 0, errors.New("failed to insert order")
+		return 0, insertError("order", err)
 	}
 
 	return result.LastInsertId()
 }
 
-func (r *orderRepository) GetOrderByID(orderId int64) (*routeModels.Order, error) {
-	query := `SELECT orderId, resLocationId, cusLocationId, prepTimeInMinutes 
-		FROM orders
-		WHERE orderId = ?`
+func (r *orderRepository) GetOrderByID(ctx context.Context, orderId int64) (*routeModels.Order, error) {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
+	query := `SELECT ` + orderColumns + `
+		FROM orders o
+		WHERE o.orderId = ?`
+
+	order, err := scanOrder(r.db.QueryRowContext(ctx, query, orderId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %d", ErrOrderNotFound, orderId)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// orderColumns - Columns read by scanOrder, orders is aliased as o
+const orderColumns = `o.orderId, o.resLocationId, o.cusLocationId, o.restaurantId, o.customerId,
+				o.prepTimeInMinutes, o.status,
+				o.promisedBy, o.slaWeight, o.size, o.weightKg, o.maxInBagMinutes,
+				o.deliverAfter, o.deliverBefore, o.createdAt, o.updatedAt`
 
+func scanOrder(row rowScanner) (*routeModels.Order, error) {
 	var order routeModels.Order
+	var promisedBy, deliverAfter, deliverBefore sql.NullTime
+	var restaurantId, customerId sql.NullInt64
 
-	err := r.db.QueryRow(query, orderId).Scan(
-		&order.CreatedAt,
+	err := row.Scan(
+		&order.OrderID,
 		&order.ResLocationID,
 		&order.CusLocationID,
+		&restaurantId,
+		&customerId,
 		&order.PrepTimeInMinutes,
+		&order.Status,
+		&promisedBy,
+		&order.SLAWeight,
+		&order.Size,
+		&order.WeightKg,
+		&order.MaxInBagMinutes,
+		&deliverAfter,
+		&deliverBefore,
+		&order.CreatedAt,
+		&order.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	if restaurantId.Valid {
+		order.RestaurantID = &restaurantId.Int64
+	}
+	if customerId.Valid {
+		order.CustomerID = &customerId.Int64
+	}
+	if promisedBy.Valid {
+		order.PromisedBy = &promisedBy.Time
+	}
+	if deliverAfter.Valid {
+		order.DeliverAfter = &deliverAfter.Time
+	}
+	if deliverBefore.Valid {
+		order.DeliverBefore = &deliverBefore.Time
+	}
+
 	return &order, nil
 }
 
-func (r *orderRepository) GetOrdersByIDs(orderIds []int64) ([]routeModels.Order, error) {
+func (r *orderRepository) GetOrdersByIDs(ctx context.Context, orderIds []int64) ([]routeModels.Order, error) {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
 	if len(orderIds) == 0 {
 		return nil, nil
 	}
 
 	placeholders := strings.Repeat("?,", len(orderIds)-1) + "?"
-	query := `SELECT orderId, resLocationId, cusLocationId, prepTimeInMinutes 
-				FROM orders
-				WHERE orderId IN (` + placeholders + `)`
+	query := `SELECT ` + orderColumns + `
+				FROM orders o
+				WHERE o.orderId IN (` + placeholders + `)`
 
 	args := make([]interface{}, len(orderIds))
-    for i, id := range orderIds {
-        args[i] = id
-    }
-
-    rows, err := r.db.Query(query, args...)
-    if err != nil {
-        return nil, err
-    }
-    defer rows.Close()
-
-    var orders []routeModels.Order
-    for rows.Next() {
-        var order routeModels.Order
-        if err := rows.Scan(&order.OrderID, &order.ResLocationID, &order.CusLocationID, &order.PrepTimeInMinutes); err != nil {
-            return nil, err
-        }
-        orders = append(orders, order)
-    }
-    if err := rows.Err(); err != nil {
-        return nil, err
-    }
-
-    return orders, nil
-}
\ No newline at end of file
+	for i, id := range orderIds {
+		args[i] = id
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []routeModels.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// GetUnassignedOrders returns open orders no rider has, oldest first
+func (r *orderRepository) GetUnassignedOrders(ctx context.Context, limit int) ([]routeModels.Order, error) {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
+	query := `SELECT ` + orderColumns + `
+		FROM orders o
+		LEFT JOIN rider_order_assignments a ON a.orderId = o.orderId
+		WHERE a.orderId IS NULL AND o.status IN (?, ?, ?, ?)
+		ORDER BY o.createdAt, o.orderId
+		LIMIT ?`
+
+	rows, err := r.db.QueryContext(ctx, query,
+		routeModels.OrderStatusCreated,
+		routeModels.OrderStatusAccepted,
+		routeModels.OrderStatusPreparing,
+		routeModels.OrderStatusReady,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := make([]routeModels.Order, 0)
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+/*
+* ListOrders - One page of orders matching filter, in filter.Sort order.
+* Paging is keyset based, the page starts right after filter.After and
+* holds at most filter.Limit orders.
+ */
+func (r *orderRepository) ListOrders(ctx context.Context, filter routeModels.OrderFilter) ([]routeModels.Order, error) {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
+	where := make([]string, 0)
+	args := make([]interface{}, 0)
+
+	if filter.CreatedFrom != nil {
+		where = append(where, `o.createdAt >= ?`)
+		args = append(args, *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		where = append(where, `o.createdAt < ?`)
+		args = append(args, *filter.CreatedTo)
+	}
+	if len(filter.Statuses) > 0 {
+		where = append(where, `o.status IN (`+strings.Repeat("?,", len(filter.Statuses)-1)+`?)`)
+		for _, status := range filter.Statuses {
+			args = append(args, status)
+		}
+	}
+	if filter.RestaurantID != nil {
+		where = append(where, `o.restaurantId = ?`)
+		args = append(args, *filter.RestaurantID)
+	}
+	if filter.RestaurantLocationID != nil {
+		where = append(where, `o.resLocationId = ?`)
+		args = append(args, *filter.RestaurantLocationID)
+	}
+	join := ``
+	if box := filter.CustomerBox; box != nil {
+		join = `JOIN locations c ON c.id = o.cusLocationId`
+		where = append(where, `c.latitude BETWEEN ? AND ?`, `c.longitude BETWEEN ? AND ?`)
+		args = append(args, box.MinLatitude, box.MaxLatitude, box.MinLongitude, box.MaxLongitude)
+	}
+
+	// Keyset paging, the page continues after the cursor in sort order
+	var orderBy string
+	switch filter.Sort {
+	case routeModels.OrderSortIDDesc:
+		orderBy = `o.orderId DESC`
+		if filter.After != nil {
+			where = append(where, `o.orderId < ?`)
+			args = append(args, filter.After.OrderID)
+		}
+	case routeModels.OrderSortCreatedAtAsc:
+		orderBy = `o.createdAt, o.orderId`
+		if filter.After != nil {
+			where = append(where, `(o.createdAt > ? OR (o.createdAt = ? AND o.orderId > ?))`)
+			args = append(args, filter.After.CreatedAt, filter.After.CreatedAt, filter.After.OrderID)
+		}
+	case routeModels.OrderSortCreatedAtDesc:
+		orderBy = `o.createdAt DESC, o.orderId DESC`
+		if filter.After != nil {
+			where = append(where, `(o.createdAt < ? OR (o.createdAt = ? AND o.orderId < ?))`)
+			args = append(args, filter.After.CreatedAt, filter.After.CreatedAt, filter.After.OrderID)
+		}
+	default:
+		orderBy = `o.orderId`
+		if filter.After != nil {
+			where = append(where, `o.orderId > ?`)
+			args = append(args, filter.After.OrderID)
+		}
+	}
+
+	query := `SELECT ` + orderColumns + `
+		FROM orders o
+		` + join
+	if len(where) > 0 {
+		query += `
+		WHERE ` + strings.Join(where, ` AND `)
+	}
+	query += `
+		ORDER BY ` + orderBy + `
+		LIMIT ?`
+	args = append(args, filter.Limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := make([]routeModels.Order, 0)
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// UpdateOrderStatus moves an order from -> to and records the transition in its history
+func (r *orderRepository) UpdateOrderStatus(ctx context.Context, orderId int64, from, to routeModels.OrderStatus) error {
+	ctx, cancel := r.deadlines.tx(ctx)
+	defer cancel()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE orders SET status = ? WHERE orderId = ? AND status = ?`, to, orderId, from)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrOrderStatusConflict
+	}
+
+	if err := insertStatusChange(ctx, tx, orderId, from, to); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// InsertOrderStatusChange records a transition without moving the order, from is empty when the order is created
+func (r *orderRepository) InsertOrderStatusChange(ctx context.Context, orderId int64, from, to routeModels.OrderStatus) error {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
+	return insertStatusChange(ctx, r.db, orderId, from, to)
+}
+
+func insertStatusChange(ctx context.Context, db DBTX, orderId int64, from, to routeModels.OrderStatus) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO order_status_history
+		(orderId, fromStatus, toStatus)
+		VALUES (?, ?, ?)`, orderId, from, to)
+	if err != nil {
+		return insertError("order status change", err)
+	}
+	return nil
+}
+
+func (r *orderRepository) GetOrderStatusHistory(ctx context.Context, orderId int64) ([]routeModels.OrderStatusChange, error) {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
+	query := `SELECT id, orderId, fromStatus, toStatus, changedAt
+		FROM order_status_history
+		WHERE orderId = ?
+		ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, orderId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make([]routeModels.OrderStatusChange, 0)
+	for rows.Next() {
+		var change routeModels.OrderStatusChange
+		if err := rows.Scan(&change.ID, &change.OrderID, &change.FromStatus, &change.ToStatus, &change.ChangedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
@@ -0,0 +1,532 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/apperrors"
+	"github.com/SHIVAMSINGH0101/go-demo/internal/config"
+	"github.com/SHIVAMSINGH0101/go-demo/internal/database"
+	routeModels "github.com/SHIVAMSINGH0101/go-demo/internal/models"
+	"github.com/go-sql-driver/mysql"
+)
+
+/*
+* testRepos - Repositories on a fresh database migrated to the latest
+* version. It is a SQLite file, or a scratch MySQL database dropped when
+* the test ends if TEST_MYSQL_DSN points at a server the tests may create
+* databases on, e.g. root:secret@tcp(localhost:3306)/.
+ */
+func testRepos(t *testing.T) (Repositories, UnitOfWork, *sql.DB) {
+	t.Helper()
+
+	driver := database.DriverSQLite
+	db, err := database.NewConnection(config.DatabaseConfig{
+		Driver:     driver,
+		SQLitePath: filepath.Join(t.TempDir(), "ordersdb.sqlite"),
+	})
+	if dsn := os.Getenv("TEST_MYSQL_DSN"); dsn != "" {
+		driver = database.DriverMySQL
+		db, err = mysqlTestDB(t, dsn)
+	}
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := database.NewMigrator(db, driver)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	repos, uow, err := NewRepositories(db, driver, testDeadlines)
+	if err != nil {
+		t.Fatalf("NewRepositories: %v", err)
+	}
+	return repos, uow, db
+}
+
+func mysqlTestDB(t *testing.T, dsn string) (*sql.DB, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	cfg.DBName = ""
+	cfg.ParseTime = true
+	cfg.ClientFoundRows = true
+
+	admin, err := sql.Open(database.DriverMySQL, cfg.FormatDSN())
+	if err != nil {
+		return nil, err
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	name := fmt.Sprintf("ordersdb_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+		return nil, err
+	}
+	t.Cleanup(func() { admin.Exec("DROP DATABASE " + name) })
+
+	cfg.DBName = name
+	return sql.Open(database.DriverMySQL, cfg.FormatDSN())
+}
+
+var testDeadlines = Deadlines{Query: 5 * time.Second, Tx: 10 * time.Second}
+
+func insertTestLocations(t *testing.T, repo OrderRepository) (int64, int64) {
+	t.Helper()
+	ctx := context.Background()
+
+	resID, err := repo.InsertLocation(ctx, &routeModels.Location{
+		Name: "Spice Hub", Latitude: 12.9716, Longitude: 77.5946, Type: routeModels.LocationTypeRestaurant,
+	})
+	if err != nil {
+		t.Fatalf("InsertLocation: %v", err)
+	}
+	cusID, err := repo.InsertLocation(ctx, &routeModels.Location{
+		Name: "Asha", Latitude: 12.9352, Longitude: 77.6245, Type: routeModels.LocationTypeCustomer,
+	})
+	if err != nil {
+		t.Fatalf("InsertLocation: %v", err)
+	}
+	return resID, cusID
+}
+
+func TestGetOrderByID(t *testing.T) {
+	repos, _, _ := testRepos(t)
+	repo := repos.Orders
+	ctx := context.Background()
+	resID, cusID := insertTestLocations(t, repo)
+
+	promisedBy := time.Now().Add(45 * time.Minute).Truncate(time.Second)
+	want := routeModels.Order{
+		ResLocationID:     resID,
+		CusLocationID:     cusID,
+		PrepTimeInMinutes: 12.5,
+		PromisedBy:        &promisedBy,
+		SLAWeight:         2,
+		Size:              3,
+		WeightKg:          4.5,
+		MaxInBagMinutes:   30,
+	}
+	before := time.Now().Add(-time.Minute)
+	orderId, err := repo.InsertOrder(ctx, &want)
+	if err != nil {
+		t.Fatalf("InsertOrder: %v", err)
+	}
+
+	got, err := repo.GetOrderByID(ctx, orderId)
+	if err != nil {
+		t.Fatalf("GetOrderByID: %v", err)
+	}
+
+	if int64(got.OrderID) != orderId {
+		t.Errorf("OrderID = %d, want %d", got.OrderID, orderId)
+	}
+	if got.ResLocationID != resID || got.CusLocationID != cusID {
+		t.Errorf("locations = %d, %d, want %d, %d", got.ResLocationID, got.CusLocationID, resID, cusID)
+	}
+	if got.RestaurantID != nil || got.CustomerID != nil {
+		t.Errorf("restaurant and customer ids = %v, %v, want nil", got.RestaurantID, got.CustomerID)
+	}
+	if got.PrepTimeInMinutes != want.PrepTimeInMinutes || got.SLAWeight != want.SLAWeight ||
+		got.Size != want.Size || got.WeightKg != want.WeightKg || got.MaxInBagMinutes != want.MaxInBagMinutes {
+		t.Errorf("order = %+v, want %+v", got, want)
+	}
+	if got.Status != routeModels.OrderStatusCreated {
+		t.Errorf("Status = %q, want %q", got.Status, routeModels.OrderStatusCreated)
+	}
+	if got.PromisedBy == nil || !got.PromisedBy.Equal(promisedBy) {
+		t.Errorf("PromisedBy = %v, want %v", got.PromisedBy, promisedBy)
+	}
+	if got.DeliverAfter != nil || got.DeliverBefore != nil {
+		t.Errorf("delivery window = %v, %v, want nil", got.DeliverAfter, got.DeliverBefore)
+	}
+	if got.CreatedAt.Before(before) {
+		t.Errorf("CreatedAt = %v, want after %v", got.CreatedAt, before)
+	}
+	if got.UpdatedAt.Before(got.CreatedAt) {
+		t.Errorf("UpdatedAt = %v, want at or after CreatedAt %v", got.UpdatedAt, got.CreatedAt)
+	}
+}
+
+func TestGetOrderByIDNotFound(t *testing.T) {
+	repos, _, _ := testRepos(t)
+	repo := repos.Orders
+
+	_, err := repo.GetOrderByID(context.Background(), 404)
+	if !errors.Is(err, ErrOrderNotFound) || !errors.Is(err, apperrors.ErrNotFound) {
+		t.Fatalf("GetOrderByID error = %v, want ErrOrderNotFound", err)
+	}
+}
+
+func TestGetLocationByID(t *testing.T) {
+	repos, _, _ := testRepos(t)
+	repo := repos.Orders
+	ctx := context.Background()
+	resID, _ := insertTestLocations(t, repo)
+
+	loc, err := repo.GetLocationByID(ctx, resID)
+	if err != nil {
+		t.Fatalf("GetLocationByID: %v", err)
+	}
+	want := routeModels.Location{
+		ID: int(resID), Name: "Spice Hub", Latitude: 12.9716, Longitude: 77.5946, Type: routeModels.LocationTypeRestaurant,
+	}
+	if *loc != want {
+		t.Errorf("location = %+v, want %+v", *loc, want)
+	}
+
+	if _, err := repo.GetLocationByID(ctx, resID+100); !errors.Is(err, ErrLocationNotFound) {
+		t.Errorf("GetLocationByID error = %v, want ErrLocationNotFound", err)
+	}
+}
+
+func TestFindOrCreateLocation(t *testing.T) {
+	repos, _, _ := testRepos(t)
+	repo := repos.Orders
+	ctx := context.Background()
+
+	loc := &routeModels.Location{Name: "Asha", Latitude: 12.9352, Longitude: 77.6245}
+	first, err := repo.FindOrCreateLocation(ctx, loc)
+	if err != nil {
+		t.Fatalf("FindOrCreateLocation: %v", err)
+	}
+	second, err := repo.FindOrCreateLocation(ctx, loc)
+	if err != nil {
+		t.Fatalf("FindOrCreateLocation: %v", err)
+	}
+	if first != second {
+		t.Errorf("FindOrCreateLocation ids = %d, %d, want the same", first, second)
+	}
+}
+
+// A stored location is only reused as the type it was stored with, or when no type is asked for
+func TestFindOrCreateLocationType(t *testing.T) {
+	repos, _, _ := testRepos(t)
+	repo := repos.Orders
+	ctx := context.Background()
+
+	hub := &routeModels.Location{Name: "Koramangala Dark Store", Latitude: 12.9352, Longitude: 77.6245, Type: routeModels.LocationTypeHub}
+	hubID, err := repo.FindOrCreateLocation(ctx, hub)
+	if err != nil {
+		t.Fatalf("FindOrCreateLocation: %v", err)
+	}
+	if again, err := repo.FindOrCreateLocation(ctx, hub); err != nil || again != hubID {
+		t.Errorf("FindOrCreateLocation of the hub again = %d, %v, want %d", again, err, hubID)
+	}
+
+	untyped := &routeModels.Location{Name: hub.Name, Latitude: hub.Latitude, Longitude: hub.Longitude}
+	if id, err := repo.FindOrCreateLocation(ctx, untyped); err != nil || id != hubID {
+		t.Errorf("FindOrCreateLocation without a type = %d, %v, want %d", id, err, hubID)
+	}
+
+	customer := &routeModels.Location{Name: hub.Name, Latitude: hub.Latitude, Longitude: hub.Longitude, Type: routeModels.LocationTypeCustomer}
+	if _, err := repo.FindOrCreateLocation(ctx, customer); !errors.Is(err, ErrLocationTypeConflict) || !errors.Is(err, apperrors.ErrConflict) {
+		t.Errorf("FindOrCreateLocation as a customer error = %v, want ErrLocationTypeConflict", err)
+	}
+}
+
+// A plain insert of a stored name and coordinates is a conflict on every driver
+func TestInsertLocationDuplicate(t *testing.T) {
+	repos, _, _ := testRepos(t)
+	repo := repos.Orders
+	insertTestLocations(t, repo)
+
+	_, err := repo.InsertLocation(context.Background(), &routeModels.Location{
+		Name: "Asha", Latitude: 12.9352, Longitude: 77.6245, Type: routeModels.LocationTypeCustomer,
+	})
+	if !errors.Is(err, apperrors.ErrConflict) {
+		t.Fatalf("InsertLocation error = %v, want ErrConflict", err)
+	}
+}
+
+func TestUpdateOrderStatus(t *testing.T) {
+	repos, _, _ := testRepos(t)
+	repo := repos.Orders
+	ctx := context.Background()
+	resID, cusID := insertTestLocations(t, repo)
+
+	orderId, err := repo.InsertOrder(ctx, &routeModels.Order{ResLocationID: resID, CusLocationID: cusID})
+	if err != nil {
+		t.Fatalf("InsertOrder: %v", err)
+	}
+
+	if err := repo.UpdateOrderStatus(ctx, orderId, routeModels.OrderStatusCreated, routeModels.OrderStatusAccepted); err != nil {
+		t.Fatalf("UpdateOrderStatus: %v", err)
+	}
+	// The order is no longer CREATED, a second writer loses
+	err = repo.UpdateOrderStatus(ctx, orderId, routeModels.OrderStatusCreated, routeModels.OrderStatusCancelled)
+	if !errors.Is(err, ErrOrderStatusConflict) {
+		t.Fatalf("UpdateOrderStatus error = %v, want ErrOrderStatusConflict", err)
+	}
+
+	history, err := repo.GetOrderStatusHistory(ctx, orderId)
+	if err != nil {
+		t.Fatalf("GetOrderStatusHistory: %v", err)
+	}
+	if len(history) != 1 || history[0].ToStatus != routeModels.OrderStatusAccepted {
+		t.Errorf("history = %+v, want one change to ACCEPTED", history)
+	}
+}
+
+func TestInsertOrderStatusChange(t *testing.T) {
+	repos, _, _ := testRepos(t)
+	repo := repos.Orders
+	ctx := context.Background()
+	resID, cusID := insertTestLocations(t, repo)
+
+	orderId, err := repo.InsertOrder(ctx, &routeModels.Order{ResLocationID: resID, CusLocationID: cusID})
+	if err != nil {
+		t.Fatalf("InsertOrder: %v", err)
+	}
+	if err := repo.InsertOrderStatusChange(ctx, orderId, "", routeModels.OrderStatusCreated); err != nil {
+		t.Fatalf("InsertOrderStatusChange: %v", err)
+	}
+	if err := repo.InsertOrderStatusChange(ctx, orderId+100, "", routeModels.OrderStatusCreated); !errors.Is(err, apperrors.ErrValidation) {
+		t.Errorf("InsertOrderStatusChange for a missing order error = %v, want ErrValidation", err)
+	}
+
+	history, err := repo.GetOrderStatusHistory(ctx, orderId)
+	if err != nil {
+		t.Fatalf("GetOrderStatusHistory: %v", err)
+	}
+	if len(history) != 1 || history[0].FromStatus != "" || history[0].ToStatus != routeModels.OrderStatusCreated {
+		t.Errorf("history = %+v, want one creation row", history)
+	}
+}
+
+func TestWithTxRollsBack(t *testing.T) {
+	_, uow, db := testRepos(t)
+	ctx := context.Background()
+
+	errFailed := errors.New("failed")
+	err := uow.WithTx(ctx, func(repos Repositories) error {
+		if _, err := repos.Orders.InsertLocation(ctx, &routeModels.Location{Name: "Asha", Latitude: 1, Longitude: 1}); err != nil {
+			return err
+		}
+		return errFailed
+	})
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("WithTx error = %v, want %v", err, errFailed)
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM locations`).Scan(&count); err != nil {
+		t.Fatalf("count locations: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("locations = %d, want 0 after rollback", count)
+	}
+}
+
+func TestListOrders(t *testing.T) {
+	repos, _, _ := testRepos(t)
+	repo := repos.Orders
+	ctx := context.Background()
+	resID, cusID := insertTestLocations(t, repo)
+	farID, err := repo.InsertLocation(ctx, &routeModels.Location{Name: "Ravi", Latitude: 13.1986, Longitude: 77.7066})
+	if err != nil {
+		t.Fatalf("InsertLocation: %v", err)
+	}
+
+	ids := make([]int64, 0)
+	for _, cus := range []int64{cusID, farID, cusID, cusID} {
+		id, err := repo.InsertOrder(ctx, &routeModels.Order{ResLocationID: resID, CusLocationID: cus})
+		if err != nil {
+			t.Fatalf("InsertOrder: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := repo.UpdateOrderStatus(ctx, ids[3], routeModels.OrderStatusCreated, routeModels.OrderStatusAccepted); err != nil {
+		t.Fatalf("UpdateOrderStatus: %v", err)
+	}
+
+	orderIDs := func(orders []routeModels.Order) []int64 {
+		got := make([]int64, 0, len(orders))
+		for _, order := range orders {
+			got = append(got, int64(order.OrderID))
+		}
+		return got
+	}
+	box := &routeModels.BoundingBox{MinLatitude: 12.9, MinLongitude: 77.6, MaxLatitude: 13, MaxLongitude: 77.7}
+
+	tests := []struct {
+		name   string
+		filter routeModels.OrderFilter
+		want   []int64
+	}{
+		{"first page", routeModels.OrderFilter{Limit: 2}, ids[:2]},
+		{"after cursor", routeModels.OrderFilter{After: &routeModels.OrderCursor{OrderID: ids[1]}, Limit: 10}, ids[2:]},
+		{"newest first", routeModels.OrderFilter{Sort: routeModels.OrderSortCreatedAtDesc, Limit: 2}, []int64{ids[3], ids[2]}},
+		{"status", routeModels.OrderFilter{Statuses: []routeModels.OrderStatus{routeModels.OrderStatusAccepted}, Limit: 10}, ids[3:]},
+		{"customer box", routeModels.OrderFilter{CustomerBox: box, Sort: routeModels.OrderSortIDDesc, Limit: 10}, []int64{ids[3], ids[2], ids[0]}},
+		{"restaurant location", routeModels.OrderFilter{RestaurantLocationID: &cusID, Limit: 10}, []int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders, err := repo.ListOrders(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("ListOrders: %v", err)
+			}
+			if got := orderIDs(orders); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("ListOrders = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssignOrders(t *testing.T) {
+	repos, _, _ := testRepos(t)
+	ctx := context.Background()
+	resID, cusID := insertTestLocations(t, repos.Orders)
+
+	orderIds := make([]int64, 0, 2)
+	for i := 0; i < 2; i++ {
+		id, err := repos.Orders.InsertOrder(ctx, &routeModels.Order{ResLocationID: resID, CusLocationID: cusID})
+		if err != nil {
+			t.Fatalf("InsertOrder: %v", err)
+		}
+		orderIds = append(orderIds, id)
+	}
+	riderIds := make([]int64, 0, 2)
+	for _, name := range []string{"Ravi", "Meena"} {
+		id, err := repos.Riders.InsertRider(ctx, &routeModels.Rider{
+			Name: name, VehicleType: "motorbike", Capacity: 4, ShiftStatus: routeModels.RiderShiftOn,
+		})
+		if err != nil {
+			t.Fatalf("InsertRider: %v", err)
+		}
+		riderIds = append(riderIds, id)
+	}
+
+	if err := repos.Riders.AssignOrders(ctx, riderIds[0], orderIds[:1]); err != nil {
+		t.Fatalf("AssignOrders: %v", err)
+	}
+	// Assigning an order the rider already has again is fine
+	if err := repos.Riders.AssignOrders(ctx, riderIds[0], orderIds); err != nil {
+		t.Fatalf("AssignOrders again: %v", err)
+	}
+
+	// Neither order moves, not even the one that was free before the first call
+	err := repos.Riders.AssignOrders(ctx, riderIds[1], orderIds)
+	if !errors.Is(err, ErrOrderAssignedElsewhere) || !errors.Is(err, apperrors.ErrConflict) {
+		t.Fatalf("AssignOrders to another rider error = %v, want ErrOrderAssignedElsewhere", err)
+	}
+	active, err := repos.Riders.GetActiveOrderIDs(ctx, riderIds[0])
+	if err != nil {
+		t.Fatalf("GetActiveOrderIDs: %v", err)
+	}
+	if len(active) != 2 {
+		t.Errorf("first rider has orders %v, want both", active)
+	}
+
+	if err := repos.Riders.UnassignOrder(ctx, riderIds[1], orderIds[0]); !errors.Is(err, ErrAssignmentNotFound) {
+		t.Errorf("UnassignOrder from the wrong rider error = %v, want ErrAssignmentNotFound", err)
+	}
+	if err := repos.Riders.UnassignOrder(ctx, riderIds[0], orderIds[0]); err != nil {
+		t.Fatalf("UnassignOrder: %v", err)
+	}
+	if err := repos.Riders.AssignOrders(ctx, riderIds[1], orderIds[:1]); err != nil {
+		t.Errorf("AssignOrders after unassigning: %v", err)
+	}
+}
+
+func TestInsertPlanVersionConflict(t *testing.T) {
+	repos, _, _ := testRepos(t)
+	ctx := context.Background()
+
+	riderId, err := repos.Riders.InsertRider(ctx, &routeModels.Rider{
+		Name: "Ravi", VehicleType: "motorbike", Capacity: 4, ShiftStatus: routeModels.RiderShiftOn,
+	})
+	if err != nil {
+		t.Fatalf("InsertRider: %v", err)
+	}
+	plan := func() *routeModels.RoutePlanVersion {
+		return &routeModels.RoutePlanVersion{RiderID: riderId, Reason: routeModels.ReplanManual, PlannedAt: time.Now()}
+	}
+
+	if version, err := repos.RoutePlans.InsertPlan(ctx, plan(), 0); err != nil || version != 1 {
+		t.Fatalf("InsertPlan first = %d, %v, want version 1", version, err)
+	}
+	if version, err := repos.RoutePlans.InsertPlan(ctx, plan(), 1); err != nil || version != 2 {
+		t.Fatalf("InsertPlan from version 1 = %d, %v, want version 2", version, err)
+	}
+
+	// A second re-plan made from version 1 lost the race
+	_, err = repos.RoutePlans.InsertPlan(ctx, plan(), 1)
+	if !errors.Is(err, ErrPlanVersionConflict) || !errors.Is(err, apperrors.ErrConflict) {
+		t.Fatalf("InsertPlan from a stale version error = %v, want ErrPlanVersionConflict", err)
+	}
+	latest, err := repos.RoutePlans.GetLatestPlan(ctx, riderId)
+	if err != nil {
+		t.Fatalf("GetLatestPlan: %v", err)
+	}
+	if latest.Version != 2 {
+		t.Errorf("latest version = %d, want 2", latest.Version)
+	}
+}
+
+func TestLockRider(t *testing.T) {
+	repos, uow, _ := testRepos(t)
+	ctx := context.Background()
+
+	riderId, err := repos.Riders.InsertRider(ctx, &routeModels.Rider{
+		Name: "Ravi", VehicleType: "motorbike", Capacity: 4, ShiftStatus: routeModels.RiderShiftOn,
+	})
+	if err != nil {
+		t.Fatalf("InsertRider: %v", err)
+	}
+
+	err = uow.WithTx(ctx, func(repos Repositories) error {
+		if err := repos.Riders.LockRider(ctx, riderId); err != nil {
+			return err
+		}
+		return repos.Riders.UpdateShiftStatus(ctx, riderId, routeModels.RiderShiftOff)
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+
+	err = uow.WithTx(ctx, func(repos Repositories) error {
+		return repos.Riders.LockRider(ctx, riderId+1)
+	})
+	if !errors.Is(err, ErrRiderNotFound) {
+		t.Errorf("LockRider of a missing rider error = %v, want ErrRiderNotFound", err)
+	}
+}
+
+// A call past its deadline fails instead of waiting on the database
+func TestQueryDeadline(t *testing.T) {
+	_, _, db := testRepos(t)
+	driver := database.DriverSQLite
+	if os.Getenv("TEST_MYSQL_DSN") != "" {
+		driver = database.DriverMySQL
+	}
+
+	repos, uow, err := NewRepositories(db, driver, Deadlines{Query: time.Nanosecond, Tx: time.Nanosecond})
+	if err != nil {
+		t.Fatalf("NewRepositories: %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := repos.Orders.GetOrderByID(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetOrderByID error = %v, want context.DeadlineExceeded", err)
+	}
+	err = uow.WithTx(ctx, func(repos Repositories) error {
+		_, err := repos.Orders.GetOrderByID(ctx, 1)
+		return err
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WithTx error = %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/apperrors"
+	routeModels "github.com/SHIVAMSINGH0101/go-demo/internal/models"
+)
+
+// Restaurant repository interacts with restaurants and their rows in locations
+type RestaurantRepository interface {
+	InsertRestaurant(ctx context.Context, restaurant *routeModels.Restaurant) (int64, error)
+	GetRestaurantByID(ctx context.Context, id int64) (*routeModels.Restaurant, error)
+}
+
+// ErrRestaurantNotFound - No restaurant with the given id
+var ErrRestaurantNotFound = apperrors.New(apperrors.ErrNotFound, "restaurant not found")
+
+type restaurantRepository struct {
+	db        DBTX
+	deadlines Deadlines
+	dialect   dialect
+}
+
+/*
+* InsertRestaurant - Stores the restaurant with its location and returns its id.
+* A restaurant already stored at the same name and coordinates is returned as is.
+ */
+func (r *restaurantRepository) InsertRestaurant(ctx context.Context, restaurant *routeModels.Restaurant) (int64, error) {
+	ctx, cancel := r.deadlines.tx(ctx)
+	defer cancel()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	restaurant.Location.Name = restaurant.Name
+	restaurant.Location.Type = routeModels.LocationTypeRestaurant
+	locationId, err := r.dialect.upsertLocationID(ctx, tx, &restaurant.Location)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := r.dialect.upsert(ctx, tx, r.dialect.upsertRestaurant, restaurant.Name, locationId)
+	if err != nil {
+		return 0, insertError("restaurant", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	restaurant.ID = id
+	restaurant.LocationID = locationId
+	restaurant.Location.ID = int(locationId)
+	return id, nil
+}
+
+func (r *restaurantRepository) GetRestaurantByID(ctx context.Context, id int64) (*routeModels.Restaurant, error) {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
+	query := `SELECT r.id, r.name, r.locationId, r.createdAt, l.id, l.name, l.latitude, l.longitude, l.type
+			FROM restaurants r
+			JOIN locations l ON l.id = r.locationId
+			WHERE r.id = ?`
+
+	var restaurant routeModels.Restaurant
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&restaurant.ID, &restaurant.Name, &restaurant.LocationID, &restaurant.CreatedAt,
+		&restaurant.Location.ID, &restaurant.Location.Name, &restaurant.Location.Latitude, &restaurant.Location.Longitude,
+		&restaurant.Location.Type)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRestaurantNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &restaurant, nil
+}
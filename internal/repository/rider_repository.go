@@ -0,0 +1,373 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/apperrors"
+	routeModels "github.com/SHIVAMSINGH0101/go-demo/internal/models"
+)
+
+// Rider repository interacts with riders and rider_order_assignments table
+type RiderRepository interface {
+	InsertRider(ctx context.Context, rider *routeModels.Rider) (int64, error)
+	GetRiderByID(ctx context.Context, id int64) (*routeModels.Rider, error)
+	LockRider(ctx context.Context, id int64) error
+	UpdateShiftStatus(ctx context.Context, id int64, status routeModels.RiderShiftStatus) error
+	UpdateLocation(ctx context.Context, loc *routeModels.RiderLocation) error
+
+	GetAvailableRiders(ctx context.Context) ([]routeModels.Rider, error)
+
+	AssignOrders(ctx context.Context, riderId int64, orderIds []int64) error
+	ClaimOrders(ctx context.Context, riderId int64, orderIds []int64) ([]int64, error)
+	UnassignOrder(ctx context.Context, riderId, orderId int64) error
+	GetActiveOrderIDs(ctx context.Context, riderId int64) ([]int64, error)
+	ListAssignments(ctx context.Context, since time.Time) ([]routeModels.RiderAssignment, error)
+}
+
+var (
+	// ErrRiderNotFound - No rider with the given id
+	ErrRiderNotFound = apperrors.New(apperrors.ErrNotFound, "rider not found")
+	// ErrOrderAssignedElsewhere - The order is with another rider, it has to be unassigned first
+	ErrOrderAssignedElsewhere = apperrors.New(apperrors.ErrConflict, "order is assigned to another rider")
+	// ErrAssignmentNotFound - The order is not assigned to the rider
+	ErrAssignmentNotFound = apperrors.New(apperrors.ErrNotFound, "order is not assigned to the rider")
+)
+
+type riderRepository struct {
+	db        DBTX
+	deadlines Deadlines
+	dialect   dialect
+}
+
+func (r *riderRepository) InsertRider(ctx context.Context, rider *routeModels.Rider) (int64, error) {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
+	query := `INSERT INTO riders
+			(name, vehicleType, capacity, maxWeightKg, shiftStatus)
+			VALUES (?, ?, ?, ?, ?)`
+
+	result, err := r.db.ExecContext(ctx, query, rider.Name, rider.VehicleType, rider.Capacity, rider.MaxWeightKg, rider.ShiftStatus)
+	if err != nil {
+		return 0, insertError("rider", err)
+	}
+
+	return result.LastInsertId()
+}
+
+const riderColumns = `id, name, vehicleType, capacity, maxWeightKg, shiftStatus,
+				lastLatitude, lastLongitude, lastLocationAt, createdAt, updatedAt`
+
+// rowScanner - Common Scan of *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRider(row rowScanner) (*routeModels.Rider, error) {
+	var rider routeModels.Rider
+	var lastLat, lastLon sql.NullFloat64
+	var lastAt sql.NullTime
+
+	err := row.Scan(
+		&rider.ID,
+		&rider.Name,
+		&rider.VehicleType,
+		&rider.Capacity,
+		&rider.MaxWeightKg,
+		&rider.ShiftStatus,
+		&lastLat,
+		&lastLon,
+		&lastAt,
+		&rider.CreatedAt,
+		&rider.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if lastAt.Valid {
+		rider.LastLatitude = lastLat.Float64
+		rider.LastLongitude = lastLon.Float64
+		rider.LastLocationAt = &lastAt.Time
+	}
+
+	return &rider, nil
+}
+
+func (r *riderRepository) GetRiderByID(ctx context.Context, id int64) (*routeModels.Rider, error) {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
+	query := `SELECT ` + riderColumns + `
+			FROM riders
+			WHERE id = ?`
+
+	rider, err := scanRider(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRiderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return rider, nil
+}
+
+// LockRider holds the rider row until the transaction it runs in ends, use it inside UnitOfWork.WithTx
+func (r *riderRepository) LockRider(ctx context.Context, id int64) error {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
+	var riderId int64
+	err := r.db.QueryRowContext(ctx, r.dialect.lockRider, id).Scan(&riderId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRiderNotFound
+	}
+	return err
+}
+
+// GetAvailableRiders returns riders on shift with a known location
+func (r *riderRepository) GetAvailableRiders(ctx context.Context) ([]routeModels.Rider, error) {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
+	query := `SELECT ` + riderColumns + `
+			FROM riders
+			WHERE shiftStatus = ? AND lastLocationAt IS NOT NULL
+			ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, routeModels.RiderShiftOn)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	riders := make([]routeModels.Rider, 0)
+	for rows.Next() {
+		rider, err := scanRider(rows)
+		if err != nil {
+			return nil, err
+		}
+		riders = append(riders, *rider)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return riders, nil
+}
+
+func (r *riderRepository) UpdateShiftStatus(ctx context.Context, id int64, status routeModels.RiderShiftStatus) error {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `UPDATE riders SET shiftStatus = ? WHERE id = ?`, status, id)
+	if err != nil {
+		return err
+	}
+	return requireRow(result, ErrRiderNotFound)
+}
+
+// UpdateLocation stores a GPS ping unless a newer one is already stored
+func (r *riderRepository) UpdateLocation(ctx context.Context, loc *routeModels.RiderLocation) error {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
+	query := `UPDATE riders
+			SET lastLatitude = ?, lastLongitude = ?, lastLocationAt = ?
+			WHERE id = ? AND (lastLocationAt IS NULL OR lastLocationAt <= ?)`
+
+	_, err := r.db.ExecContext(ctx, query, loc.Latitude, loc.Longitude, loc.RecordedAt, loc.RiderID, loc.RecordedAt)
+	return err
+}
+
+/*
+* AssignOrders hands the orders to the rider. Orders the rider already has
+* keep their assignment, and if any order is with another rider nothing is
+* assigned and ErrOrderAssignedElsewhere names them.
+ */
+func (r *riderRepository) AssignOrders(ctx context.Context, riderId int64, orderIds []int64) error {
+	ctx, cancel := r.deadlines.tx(ctx)
+	defer cancel()
+
+	if len(orderIds) == 0 {
+		return nil
+	}
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	placeholders := strings.Repeat("(?, ?, ?),", len(orderIds)-1) + "(?, ?, ?)"
+	query := `INSERT INTO rider_order_assignments
+			(riderId, orderId, assignedAt)
+			VALUES ` + placeholders + `
+			` + r.dialect.keepAssigned
+
+	now := time.Now()
+	args := make([]interface{}, 0, 3*len(orderIds))
+	for _, id := range orderIds {
+		args = append(args, riderId, id, now)
+	}
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return insertError("rider assignment", err)
+	}
+
+	// The insert holds the orders' assignment rows, so no other rider can take them before the commit
+	held, err := assignedElsewhere(ctx, tx, riderId, orderIds)
+	if err != nil {
+		return err
+	}
+	if len(held) > 0 {
+		return fmt.Errorf("%w: %v", ErrOrderAssignedElsewhere, held)
+	}
+
+	return tx.Commit()
+}
+
+// assignedElsewhere returns the orders among orderIds that another rider has
+func assignedElsewhere(ctx context.Context, db DBTX, riderId int64, orderIds []int64) ([]int64, error) {
+	placeholders := strings.Repeat("?,", len(orderIds)-1) + "?"
+	args := make([]interface{}, 0, len(orderIds)+1)
+	args = append(args, riderId)
+	for _, id := range orderIds {
+		args = append(args, id)
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT orderId
+			FROM rider_order_assignments
+			WHERE riderId <> ? AND orderId IN (`+placeholders+`)
+			ORDER BY orderId`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	held := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		held = append(held, id)
+	}
+	return held, rows.Err()
+}
+
+// ClaimOrders assigns the orders that no rider has yet and returns the ones it got
+func (r *riderRepository) ClaimOrders(ctx context.Context, riderId int64, orderIds []int64) ([]int64, error) {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
+	now := time.Now()
+	claimed := make([]int64, 0, len(orderIds))
+	for _, id := range orderIds {
+		result, err := r.db.ExecContext(ctx, r.dialect.claimOrder, riderId, id, now)
+		if err != nil {
+			return claimed, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return claimed, err
+		}
+		if affected > 0 {
+			claimed = append(claimed, id)
+		}
+	}
+
+	return claimed, nil
+}
+
+func (r *riderRepository) UnassignOrder(ctx context.Context, riderId, orderId int64) error {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM rider_order_assignments WHERE riderId = ? AND orderId = ?`, riderId, orderId)
+	if err != nil {
+		return err
+	}
+	return requireRow(result, ErrAssignmentNotFound)
+}
+
+// GetActiveOrderIDs returns the rider's assigned orders that are not delivered or cancelled
+func (r *riderRepository) GetActiveOrderIDs(ctx context.Context, riderId int64) ([]int64, error) {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
+	query := `SELECT o.orderId
+			FROM rider_order_assignments a
+			JOIN orders o ON o.orderId = a.orderId
+			WHERE a.riderId = ? AND o.status NOT IN (?, ?)
+			ORDER BY a.assignedAt, o.orderId`
+
+	rows, err := r.db.QueryContext(ctx, query, riderId, routeModels.OrderStatusDelivered, routeModels.OrderStatusCancelled)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// requireRow returns notFound when an UPDATE matched no row
+func requireRow(result sql.Result, notFound error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// ListAssignments returns assignments made at or after since, newest first
+func (r *riderRepository) ListAssignments(ctx context.Context, since time.Time) ([]routeModels.RiderAssignment, error) {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
+	query := `SELECT riderId, orderId, assignedAt
+			FROM rider_order_assignments
+			WHERE assignedAt >= ?
+			ORDER BY assignedAt DESC, id DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assignments := make([]routeModels.RiderAssignment, 0)
+	for rows.Next() {
+		var a routeModels.RiderAssignment
+		if err := rows.Scan(&a.RiderID, &a.OrderID, &a.AssignedAt); err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return assignments, nil
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/apperrors"
+	routeModels "github.com/SHIVAMSINGH0101/go-demo/internal/models"
+)
+
+// Route plan repository interacts with rider_route_plans table
+type RoutePlanRepository interface {
+	InsertPlan(ctx context.Context, plan *routeModels.RoutePlanVersion, previousVersion int) (int, error)
+	GetLatestPlan(ctx context.Context, riderId int64) (*routeModels.RoutePlanVersion, error)
+	ListPlans(ctx context.Context, riderId int64) ([]routeModels.RoutePlanVersion, error)
+}
+
+var (
+	// ErrPlanNotFound - The rider has no stored plan
+	ErrPlanNotFound = apperrors.New(apperrors.ErrNotFound, "route plan not found")
+	// ErrPlanVersionConflict - Another plan was stored since the one a re-plan started from
+	ErrPlanVersionConflict = apperrors.New(apperrors.ErrConflict, "route plan changed concurrently")
+)
+
+type routePlanRepository struct {
+	db        DBTX
+	deadlines Deadlines
+	dialect   dialect
+}
+
+/*
+* InsertPlan - Stores the plan as the rider's next version and returns it.
+* previousVersion is the version the plan was made from, 0 for the first.
+* The rider row is locked while the latest version is compared with it, so
+* of two re-plans from the same version only one is stored, on any replica.
+ */
+func (r *routePlanRepository) InsertPlan(ctx context.Context, plan *routeModels.RoutePlanVersion, previousVersion int) (int, error) {
+	ctx, cancel := r.deadlines.tx(ctx)
+	defer cancel()
+
+	steps, err := json.Marshal(plan.Steps)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var riderId int64
+	if err := tx.QueryRowContext(ctx, r.dialect.lockRider, plan.RiderID).Scan(&riderId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrRiderNotFound
+		}
+		return 0, err
+	}
+
+	var latest int
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM rider_route_plans WHERE riderId = ?`, plan.RiderID).Scan(&latest); err != nil {
+		return 0, err
+	}
+	if latest != previousVersion {
+		return 0, ErrPlanVersionConflict
+	}
+	version := latest + 1
+
+	query := `INSERT INTO rider_route_plans
+			(riderId, version, reason, plannedAt, startLatitude, startLongitude, totalTimeMinutes, steps)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := tx.ExecContext(ctx, query, plan.RiderID, version, plan.Reason, plan.PlannedAt, plan.StartLatitude,
+		plan.StartLongitude, plan.TotalTimeMinutes, steps)
+	if err != nil {
+		return 0, insertError("route plan", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	plan.ID = id
+	plan.Version = version
+	return version, nil
+}
+
+const routePlanColumns = `id, riderId, version, reason, plannedAt, startLatitude, startLongitude,
+				totalTimeMinutes, steps, createdAt`
+
+func scanRoutePlan(row rowScanner) (*routeModels.RoutePlanVersion, error) {
+	var plan routeModels.RoutePlanVersion
+	var steps []byte
+	err := row.Scan(&plan.ID, &plan.RiderID, &plan.Version, &plan.Reason, &plan.PlannedAt, &plan.StartLatitude,
+		&plan.StartLongitude, &plan.TotalTimeMinutes, &steps, &plan.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(steps, &plan.Steps); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+func (r *routePlanRepository) GetLatestPlan(ctx context.Context, riderId int64) (*routeModels.RoutePlanVersion, error) {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
+	query := `SELECT ` + routePlanColumns + `
+			FROM rider_route_plans
+			WHERE riderId = ?
+			ORDER BY version DESC
+			LIMIT 1`
+
+	plan, err := scanRoutePlan(r.db.QueryRowContext(ctx, query, riderId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPlanNotFound
+	}
+	return plan, err
+}
+
+// ListPlans - Every version of the rider's plan, newest first
+func (r *routePlanRepository) ListPlans(ctx context.Context, riderId int64) ([]routeModels.RoutePlanVersion, error) {
+	ctx, cancel := r.deadlines.query(ctx)
+	defer cancel()
+
+	query := `SELECT ` + routePlanColumns + `
+			FROM rider_route_plans
+			WHERE riderId = ?
+			ORDER BY version DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, riderId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plans := make([]routeModels.RoutePlanVersion, 0)
+	for rows.Next() {
+		plan, err := scanRoutePlan(rows)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, *plan)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return plans, nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// DBTX - What repositories need from the database, met by both *sql.DB and *sql.Tx
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+/*
+* Deadlines - How long a repository call may run before it is cancelled.
+* Query bounds a single call, Tx a whole transaction. 0 means no limit,
+* the caller's context still applies either way.
+ */
+type Deadlines struct {
+	Query time.Duration
+	Tx    time.Duration
+}
+
+func (d Deadlines) query(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withDeadline(ctx, d.Query)
+}
+
+func (d Deadlines) tx(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withDeadline(ctx, d.Tx)
+}
+
+func withDeadline(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Repositories - Repositories sharing one transaction inside WithTx
+type Repositories struct {
+	Orders      OrderRepository
+	Restaurants RestaurantRepository
+	Customers   CustomerRepository
+	Riders      RiderRepository
+	RoutePlans  RoutePlanRepository
+}
+
+// UnitOfWork - Runs repository calls that are committed or rolled back together
+type UnitOfWork interface {
+	WithTx(ctx context.Context, fn func(repos Repositories) error) error
+}
+
+type unitOfWork struct {
+	db        *sql.DB
+	deadlines Deadlines
+	dialect   dialect
+}
+
+/*
+* WithTx - Runs fn with repositories bound to a new transaction. It is
+* committed when fn returns nil and rolled back when fn fails or panics,
+* or when ctx is done before the commit.
+ */
+func (u *unitOfWork) WithTx(ctx context.Context, fn func(repos Repositories) error) error {
+	ctx, cancel := u.deadlines.tx(ctx)
+	defer cancel()
+
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	repos := bindRepositories(tx, u.deadlines, u.dialect)
+	if err := fn(repos); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+/*
+* txScope - Transaction of a single repository call. Inside WithTx the call
+* joins the outer transaction, and leaves commit and rollback to WithTx.
+ */
+type txScope struct {
+	DBTX
+	tx *sql.Tx
+}
+
+func begin(ctx context.Context, db DBTX) (*txScope, error) {
+	conn, ok := db.(*sql.DB)
+	if !ok {
+		return &txScope{DBTX: db}, nil
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &txScope{DBTX: tx, tx: tx}, nil
+}
+
+func (t *txScope) Commit() error {
+	if t.tx == nil {
+		return nil
+	}
+	return t.tx.Commit()
+}
+
+func (t *txScope) Rollback() error {
+	if t.tx == nil {
+		return nil
+	}
+	return t.tx.Rollback()
+}
@@ -0,0 +1,434 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/config"
+	"github.com/SHIVAMSINGH0101/go-demo/internal/events"
+	orderModel "github.com/SHIVAMSINGH0101/go-demo/internal/models"
+	"github.com/SHIVAMSINGH0101/go-demo/internal/repository"
+	"github.com/SHIVAMSINGH0101/go-demo/internal/utils"
+)
+
+// Dispatch methods accepted in config.DispatchConfig
+const (
+	DispatchGreedy    = "greedy"
+	DispatchHungarian = "hungarian"
+)
+
+// This is DispatchService layer
+// Matches unassigned orders to available riders on every tick
+type DispatchService interface {
+	RunOnce(ctx context.Context) (*DispatchResult, error)
+	Run(ctx context.Context)
+	ListAssignments(ctx context.Context, since time.Time) ([]orderModel.RiderAssignment, error)
+}
+
+// DispatchResult - What a single dispatch tick decided
+type DispatchResult struct {
+	RunAt              time.Time            `json:"runAt"`
+	Method             string               `json:"method"`
+	Assignments        []DispatchAssignment `json:"assignments"`
+	UnassignedOrderIDs []int64              `json:"unassignedOrderIds"`
+}
+
+// DispatchAssignment - A bundle of orders given to a rider and the minutes it adds to their route
+type DispatchAssignment struct {
+	RiderID      int64   `json:"riderId"`
+	OrderIDs     []int64 `json:"orderIds"`
+	ExtraMinutes float64 `json:"extraMinutes"`
+}
+
+type dispatchService struct {
+	orders repository.OrderRepository
+	riders repository.RiderRepository
+	routes RouteService
+	uow    repository.UnitOfWork
+	broker *events.Broker
+	cfg    config.DispatchConfig
+
+	// mu keeps a manual run and the ticker from dispatching at the same time
+	mu sync.Mutex
+}
+
+func NewDispatchService(
+	orders repository.OrderRepository,
+	riders repository.RiderRepository,
+	routes RouteService,
+	uow repository.UnitOfWork,
+	broker *events.Broker,
+	cfg config.DispatchConfig,
+) (DispatchService, error) {
+	if cfg.Method != DispatchGreedy && cfg.Method != DispatchHungarian {
+		return nil, fmt.Errorf("unknown dispatch method %q", cfg.Method)
+	}
+	if cfg.IntervalSeconds <= 0 || cfg.MaxBundleSize <= 0 || cfg.CandidateRiders <= 0 || cfg.BatchSize <= 0 {
+		return nil, fmt.Errorf("dispatch interval, bundle size, candidate riders and batch size must be positive")
+	}
+
+	return &dispatchService{
+		orders: orders,
+		riders: riders,
+		routes: routes,
+		uow:    uow,
+		broker: broker,
+		cfg:    cfg,
+	}, nil
+}
+
+// Run - Dispatches every IntervalSeconds until ctx is done
+func (s *dispatchService) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(s.cfg.IntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := s.RunOnce(ctx)
+			if err != nil {
+				log.Printf("dispatch tick failed, err %+v", err)
+				continue
+			}
+			if len(result.Assignments) > 0 {
+				log.Printf("dispatch assigned %d bundles, %d orders left", len(result.Assignments), len(result.UnassignedOrderIDs))
+			}
+		}
+	}
+}
+
+func (s *dispatchService) ListAssignments(ctx context.Context, since time.Time) ([]orderModel.RiderAssignment, error) {
+	return s.riders.ListAssignments(ctx, since)
+}
+
+// errBundleTaken - Some order of the bundle was assigned since the tick loaded it
+var errBundleTaken = errors.New("bundle is partly assigned")
+
+// riderCandidate - An available rider with their current route
+type riderCandidate struct {
+	rider     orderModel.Rider
+	plan      *utils.RoutePlan
+	freeUnits int
+}
+
+// orderBundle - Unassigned orders that are offered to a rider together
+type orderBundle struct {
+	orders    []orderModel.Order
+	locations []orderModel.Location
+}
+
+// bundleOffer - Extra route minutes if a rider takes a bundle
+type bundleOffer struct {
+	rider  int
+	bundle int
+	extra  float64
+}
+
+/*
+* RunOnce - One dispatch tick.
+* 1. Loads unassigned orders and on-shift riders with their current routes
+* 2. Builds bundles: every order alone plus it with its nearest other orders
+* 3. Prices each bundle for its nearest riders by inserting it into their route
+* 4. Picks assignments greedily by extra minutes per order, or with the
+*    Hungarian algorithm on single orders
+* 5. Persists them and publishes an orders_assigned event per rider. A bundle
+*    is claimed whole or not at all, so its extra minutes stay the price paid
+ */
+func (s *dispatchService) RunOnce(ctx context.Context) (*DispatchResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	result := &DispatchResult{
+		RunAt:              now,
+		Method:             s.cfg.Method,
+		Assignments:        []DispatchAssignment{},
+		UnassignedOrderIDs: []int64{},
+	}
+
+	orders, err := s.orders.GetUnassignedOrders(ctx, s.cfg.BatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch unassigned orders: %w", err)
+	}
+	if len(orders) == 0 {
+		return result, nil
+	}
+
+	candidates, err := s.loadRiders(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	locations, err := s.loadLocations(ctx, orders)
+	if err != nil {
+		return nil, err
+	}
+
+	bundleSize := s.cfg.MaxBundleSize
+	if s.cfg.Method == DispatchHungarian {
+		bundleSize = 1
+	}
+	bundles := buildBundles(orders, locations, bundleSize)
+	offers := s.priceBundles(candidates, bundles)
+
+	var picked []bundleOffer
+	if s.cfg.Method == DispatchHungarian {
+		picked = pickHungarian(offers, len(candidates), len(bundles))
+	} else {
+		picked = pickGreedy(offers, bundles)
+	}
+
+	assigned := make(map[int]struct{})
+	for _, offer := range picked {
+		rider := candidates[offer.rider].rider
+		orderIDs := make([]int64, 0, len(bundles[offer.bundle].orders))
+		for _, order := range bundles[offer.bundle].orders {
+			orderIDs = append(orderIDs, int64(order.OrderID))
+		}
+
+		// Someone may have assigned some of these by hand since they were loaded,
+		// then nothing is claimed and the rest of the bundle is priced again next tick
+		err := s.uow.WithTx(ctx, func(repos repository.Repositories) error {
+			claimed, err := repos.Riders.ClaimOrders(ctx, rider.ID, orderIDs)
+			if err != nil {
+				return err
+			}
+			if len(claimed) < len(orderIDs) {
+				return errBundleTaken
+			}
+			return nil
+		})
+		if errors.Is(err, errBundleTaken) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to persist assignment for rider %d: %w", rider.ID, err)
+		}
+		for _, id := range orderIDs {
+			assigned[int(id)] = struct{}{}
+		}
+
+		assignment := DispatchAssignment{
+			RiderID:      rider.ID,
+			OrderIDs:     orderIDs,
+			ExtraMinutes: offer.extra,
+		}
+		result.Assignments = append(result.Assignments, assignment)
+		s.broker.Publish(events.TypeOrdersAssigned, assignment)
+	}
+
+	for _, order := range orders {
+		if _, ok := assigned[order.OrderID]; !ok {
+			result.UnassignedOrderIDs = append(result.UnassignedOrderIDs, int64(order.OrderID))
+		}
+	}
+
+	return result, nil
+}
+
+// loadRiders - On-shift riders with bag units to spare, each with their current route planned
+func (s *dispatchService) loadRiders(ctx context.Context, now time.Time) ([]riderCandidate, error) {
+	riders, err := s.riders.GetAvailableRiders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch riders: %w", err)
+	}
+
+	candidates := make([]riderCandidate, 0, len(riders))
+	for _, rider := range riders {
+		plan, err := s.routes.PlanRoute(ctx, RouteRequest{RiderID: rider.ID, Now: now})
+		if err != nil {
+			log.Printf("dispatch skipping rider %d, err %+v", rider.ID, err)
+			continue
+		}
+
+		free := rider.Capacity - plan.BagUnits()
+		if free <= 0 {
+			continue
+		}
+		candidates = append(candidates, riderCandidate{rider: rider, plan: plan, freeUnits: free})
+	}
+
+	return candidates, nil
+}
+
+func (s *dispatchService) loadLocations(ctx context.Context, orders []orderModel.Order) (map[int64]orderModel.Location, error) {
+	locIDs := make([]int64, 0, 2*len(orders))
+	for _, order := range orders {
+		locIDs = append(locIDs, order.ResLocationID, order.CusLocationID)
+	}
+
+	locations, err := s.orders.GetLocationsByIDs(ctx, locIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch locations: %w", err)
+	}
+
+	byID := make(map[int64]orderModel.Location, len(locations))
+	for _, loc := range locations {
+		byID[int64(loc.ID)] = loc
+	}
+	return byID, nil
+}
+
+// buildBundles - Every order alone, plus it with its 1..maxSize-1 nearest orders by restaurant
+func buildBundles(orders []orderModel.Order, locations map[int64]orderModel.Location, maxSize int) []orderBundle {
+	restaurantDist := func(a, b orderModel.Order) float64 {
+		return utils.DistanceInKm(locations[a.ResLocationID], locations[b.ResLocationID])
+	}
+
+	bundles := make([]orderBundle, 0, len(orders)*maxSize)
+	seen := make(map[string]struct{})
+
+	for i, seed := range orders {
+		nearest := make([]int, 0, len(orders)-1)
+		for j := range orders {
+			if j != i {
+				nearest = append(nearest, j)
+			}
+		}
+		sort.Slice(nearest, func(a, b int) bool {
+			return restaurantDist(seed, orders[nearest[a]]) < restaurantDist(seed, orders[nearest[b]])
+		})
+
+		members := []int{i}
+		for size := 1; size <= maxSize && size <= len(orders); size++ {
+			if size > 1 {
+				members = append(members, nearest[size-2])
+			}
+
+			key := bundleKey(members)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+
+			bundle := orderBundle{}
+			for _, m := range members {
+				bundle.orders = append(bundle.orders, orders[m])
+				bundle.locations = append(bundle.locations,
+					locations[orders[m].ResLocationID],
+					locations[orders[m].CusLocationID],
+				)
+			}
+			bundles = append(bundles, bundle)
+		}
+	}
+
+	return bundles
+}
+
+func bundleKey(members []int) string {
+	sorted := append([]int(nil), members...)
+	sort.Ints(sorted)
+	return fmt.Sprint(sorted)
+}
+
+// priceBundles - Insertion cost of each bundle for its CandidateRiders nearest riders with room for it
+func (s *dispatchService) priceBundles(candidates []riderCandidate, bundles []orderBundle) []bundleOffer {
+	offers := make([]bundleOffer, 0)
+	maxExtra := float64(s.cfg.MaxExtraMinutes)
+
+	for b, bundle := range bundles {
+		pickup := bundle.locations[0]
+		units := utils.TotalBagUnits(bundle.orders)
+		nearby := make([]int, 0, len(candidates))
+		for r, c := range candidates {
+			if c.freeUnits >= units {
+				nearby = append(nearby, r)
+			}
+		}
+		sort.Slice(nearby, func(i, j int) bool {
+			return riderDistance(candidates[nearby[i]].rider, pickup) < riderDistance(candidates[nearby[j]].rider, pickup)
+		})
+		if len(nearby) > s.cfg.CandidateRiders {
+			nearby = nearby[:s.cfg.CandidateRiders]
+		}
+
+		for _, r := range nearby {
+			extra, _, err := candidates[r].plan.InsertionCost(bundle.orders, bundle.locations)
+			if err != nil {
+				continue
+			}
+			if extra/float64(len(bundle.orders)) > maxExtra {
+				continue
+			}
+			offers = append(offers, bundleOffer{rider: r, bundle: b, extra: extra})
+		}
+	}
+
+	return offers
+}
+
+func riderDistance(rider orderModel.Rider, loc orderModel.Location) float64 {
+	from := orderModel.Location{Latitude: rider.LastLatitude, Longitude: rider.LastLongitude}
+	return utils.DistanceInKm(from, loc)
+}
+
+// pickGreedy - Cheapest extra minutes per order first, one bundle per rider, no order twice
+func pickGreedy(offers []bundleOffer, bundles []orderBundle) []bundleOffer {
+	sort.SliceStable(offers, func(i, j int) bool {
+		return offers[i].extra/float64(len(bundles[offers[i].bundle].orders)) <
+			offers[j].extra/float64(len(bundles[offers[j].bundle].orders))
+	})
+
+	usedRiders := make(map[int]struct{})
+	usedOrders := make(map[int]struct{})
+	picked := make([]bundleOffer, 0)
+
+	for _, offer := range offers {
+		if _, ok := usedRiders[offer.rider]; ok {
+			continue
+		}
+		free := true
+		for _, order := range bundles[offer.bundle].orders {
+			if _, ok := usedOrders[order.OrderID]; ok {
+				free = false
+				break
+			}
+		}
+		if !free {
+			continue
+		}
+
+		usedRiders[offer.rider] = struct{}{}
+		for _, order := range bundles[offer.bundle].orders {
+			usedOrders[order.OrderID] = struct{}{}
+		}
+		picked = append(picked, offer)
+	}
+
+	return picked
+}
+
+// pickHungarian - Minimum total extra minutes matching of riders to single order bundles
+func pickHungarian(offers []bundleOffer, riderCount, bundleCount int) []bundleOffer {
+	if riderCount == 0 || bundleCount == 0 {
+		return nil
+	}
+
+	cost := make([][]float64, riderCount)
+	for r := range cost {
+		cost[r] = make([]float64, bundleCount)
+		for b := range cost[r] {
+			cost[r][b] = math.Inf(1)
+		}
+	}
+	for _, offer := range offers {
+		cost[offer.rider][offer.bundle] = offer.extra
+	}
+
+	picked := make([]bundleOffer, 0)
+	for r, b := range utils.HungarianAssignment(cost) {
+		if b >= 0 {
+			picked = append(picked, bundleOffer{rider: r, bundle: b, extra: cost[r][b]})
+		}
+	}
+	return picked
+}
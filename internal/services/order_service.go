@@ -1,53 +1,469 @@
-
 package services
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/apperrors"
 	orderModel "github.com/SHIVAMSINGH0101/go-demo/internal/models"
-	 "github.com/SHIVAMSINGH0101/go-demo/internal/repository"
+	"github.com/SHIVAMSINGH0101/go-demo/internal/repository"
 )
+
 // This is OrderService layer
 // All the business logic related to Order are performed
 type OrderService interface {
-	CreateLocation(loc *orderModel.Location) (int64, error)
-	GetLocationByID(id int64) (*orderModel.Location, error)
-	GetLocationsByIDs(ids []int64) ([]orderModel.Location, error)
+	CreateLocation(ctx context.Context, loc *orderModel.Location) (int64, error)
+	GetLocationByID(ctx context.Context, id int64) (*orderModel.Location, error)
+	GetLocationsByIDs(ctx context.Context, ids []int64) ([]orderModel.Location, error)
+	CreateHub(ctx context.Context, hub *orderModel.Location) (int64, error)
+	GetHubs(ctx context.Context) ([]orderModel.Location, error)
+
+	CreateRestaurant(ctx context.Context, restaurant *orderModel.Restaurant) (int64, error)
+	GetRestaurantByID(ctx context.Context, id int64) (*orderModel.Restaurant, error)
+	CreateCustomer(ctx context.Context, customer *orderModel.Customer) (int64, error)
+	GetCustomerByID(ctx context.Context, id int64) (*orderModel.Customer, error)
+
+	CreateOrder(ctx context.Context, order *orderModel.Order) (int64, error)
+	PlaceOrder(ctx context.Context, req *PlaceOrderRequest) (int64, error)
+	GetOrderByID(ctx context.Context, orderId int64) (*orderModel.Order, error)
+	GetOrderDetails(ctx context.Context, orderId int64) (*orderModel.OrderDetails, error)
+	GetOrdersByIDs(ctx context.Context, ids []int64) ([]orderModel.Order, error)
+	ListOrders(ctx context.Context, filter orderModel.OrderFilter, cursor string) (*OrderPage, error)
+
+	UpdateOrderStatus(ctx context.Context, orderId int64, status orderModel.OrderStatus) (*orderModel.Order, error)
+	GetOrderStatusHistory(ctx context.Context, orderId int64) ([]orderModel.OrderStatusChange, error)
+}
 
-	CreateOrder(order *orderModel.Order) (int64, error)
-	GetOrderByID(orderId int64) (*orderModel.Order, error)
-	GetOrdersByIDs(ids []int64) ([]orderModel.Order, error)
+var (
+	ErrOrderNotFound           = repository.ErrOrderNotFound
+	ErrUnknownOrderStatus      = apperrors.New(apperrors.ErrValidation, "unknown order status")
+	ErrInvalidStatusTransition = apperrors.New(apperrors.ErrConflict, "invalid order status transition")
+	ErrInvalidLocation         = apperrors.New(apperrors.ErrValidation, "invalid location")
+	ErrInvalidOrder            = apperrors.New(apperrors.ErrValidation, "invalid order")
+	ErrInvalidOrderFilter      = apperrors.New(apperrors.ErrValidation, "invalid order filter")
+)
+
+// orderStatusTransitions - Legal next states for every order state
+var orderStatusTransitions = map[orderModel.OrderStatus][]orderModel.OrderStatus{
+	orderModel.OrderStatusCreated:   {orderModel.OrderStatusAccepted, orderModel.OrderStatusCancelled},
+	orderModel.OrderStatusAccepted:  {orderModel.OrderStatusPreparing, orderModel.OrderStatusCancelled},
+	orderModel.OrderStatusPreparing: {orderModel.OrderStatusReady, orderModel.OrderStatusCancelled},
+	orderModel.OrderStatusReady:     {orderModel.OrderStatusPickedUp, orderModel.OrderStatusCancelled},
+	orderModel.OrderStatusPickedUp:  {orderModel.OrderStatusDelivered},
+	orderModel.OrderStatusDelivered: {},
+	orderModel.OrderStatusCancelled: {},
+}
+
+func canTransition(from, to orderModel.OrderStatus) bool {
+	for _, next := range orderStatusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
 }
 
 type orderService struct {
-	repo repository.OrderRepository
+	repo        repository.OrderRepository
+	restaurants repository.RestaurantRepository
+	customers   repository.CustomerRepository
+	uow         repository.UnitOfWork
 }
 
-func NewOrderService(r repository.OrderRepository) OrderService {
+func NewOrderService(
+	r repository.OrderRepository,
+	restaurants repository.RestaurantRepository,
+	customers repository.CustomerRepository,
+	uow repository.UnitOfWork,
+) OrderService {
 	return &orderService{
-		repo: r,
+		repo:        r,
+		restaurants: restaurants,
+		customers:   customers,
+		uow:         uow,
+	}
+}
+
+// CreateLocation - Returns the stored location with the same name and coordinates, or stores a new one
+func (s *orderService) CreateLocation(ctx context.Context, loc *orderModel.Location) (int64, error) {
+	return s.repo.FindOrCreateLocation(ctx, loc)
+}
+
+func (s *orderService) GetLocationByID(ctx context.Context, id int64) (*orderModel.Location, error) {
+	return s.repo.GetLocationByID(ctx, id)
+}
+
+func (s *orderService) GetLocationsByIDs(ctx context.Context, ids []int64) ([]orderModel.Location, error) {
+	return s.repo.GetLocationsByIDs(ctx, ids)
+}
+
+// CreateHub - Stores a hub or dark store, a location routes can start or end at.
+// The same hub created again returns the stored one
+func (s *orderService) CreateHub(ctx context.Context, hub *orderModel.Location) (int64, error) {
+	if err := validateLocation(hub.Name, hub); err != nil {
+		return 0, err
+	}
+	hub.Type = orderModel.LocationTypeHub
+	return s.repo.FindOrCreateLocation(ctx, hub)
+}
+
+func (s *orderService) GetHubs(ctx context.Context) ([]orderModel.Location, error) {
+	return s.repo.GetLocationsByType(ctx, orderModel.LocationTypeHub)
+}
+
+// CreateRestaurant - Stores a restaurant, creating it again returns the same id
+func (s *orderService) CreateRestaurant(ctx context.Context, restaurant *orderModel.Restaurant) (int64, error) {
+	if err := validateLocation(restaurant.Name, &restaurant.Location); err != nil {
+		return 0, err
+	}
+	return s.restaurants.InsertRestaurant(ctx, restaurant)
+}
+
+func (s *orderService) GetRestaurantByID(ctx context.Context, id int64) (*orderModel.Restaurant, error) {
+	return s.restaurants.GetRestaurantByID(ctx, id)
+}
+
+// CreateCustomer - Stores a customer with their delivery address
+func (s *orderService) CreateCustomer(ctx context.Context, customer *orderModel.Customer) (int64, error) {
+	if err := validateLocation(customer.Name, &customer.Location); err != nil {
+		return 0, err
+	}
+	return s.customers.InsertCustomer(ctx, customer)
+}
+
+func (s *orderService) GetCustomerByID(ctx context.Context, id int64) (*orderModel.Customer, error) {
+	return s.customers.GetCustomerByID(ctx, id)
+}
+
+func validateLocation(name string, loc *orderModel.Location) error {
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidLocation)
+	}
+	if loc.Latitude < -90 || loc.Latitude > 90 || loc.Longitude < -180 || loc.Longitude > 180 {
+		return fmt.Errorf("%w: coordinates out of range", ErrInvalidLocation)
+	}
+	return nil
+}
+
+// validateOrder - Rejects negative times and loads and a delivery window that closes before it opens
+func validateOrder(order *orderModel.Order) error {
+	if order.PrepTimeInMinutes < 0 || order.MaxInBagMinutes < 0 {
+		return fmt.Errorf("%w: prep time and max in-bag minutes can not be negative", ErrInvalidOrder)
+	}
+	if order.Size < 0 || order.WeightKg < 0 || order.SLAWeight < 0 {
+		return fmt.Errorf("%w: size, weight and sla weight can not be negative", ErrInvalidOrder)
+	}
+	if order.DeliverAfter != nil && order.DeliverBefore != nil && !order.DeliverBefore.After(*order.DeliverAfter) {
+		return fmt.Errorf("%w: deliver_before must be after deliver_after", ErrInvalidOrder)
+	}
+	return nil
+}
+
+func (s *orderService) CreateOrder(ctx context.Context, order *orderModel.Order) (int64, error) {
+	if err := validateOrder(order); err != nil {
+		return 0, err
+	}
+	var orderId int64
+	err := s.uow.WithTx(ctx, func(repos repository.Repositories) error {
+		id, err := insertOrder(ctx, repos, order)
+		orderId = id
+		return err
+	})
+	return orderId, err
+}
+
+// insertOrder - Stores the order with the CREATED row that starts its status history
+func insertOrder(ctx context.Context, repos repository.Repositories, order *orderModel.Order) (int64, error) {
+	orderId, err := repos.Orders.InsertOrder(ctx, order)
+	if err != nil {
+		return 0, err
+	}
+	if err := repos.Orders.InsertOrderStatusChange(ctx, orderId, "", orderModel.OrderStatusCreated); err != nil {
+		return 0, err
+	}
+	return orderId, nil
+}
+
+/*
+* PlaceOrderRequest - An order with its pickup and drop. A stored restaurant
+* or customer is given by id, otherwise by name and coordinates.
+ */
+type PlaceOrderRequest struct {
+	RestaurantID *int64
+	Restaurant   orderModel.Location
+	CustomerID   *int64
+	Customer     orderModel.Location
+	Order        orderModel.Order
+}
+
+/*
+* PlaceOrder - Finds or creates the restaurant and customer locations and
+* stores the order in one transaction, nothing is kept when any step fails.
+ */
+func (s *orderService) PlaceOrder(ctx context.Context, req *PlaceOrderRequest) (int64, error) {
+	order := req.Order
+	if err := validateOrder(&order); err != nil {
+		return 0, err
+	}
+	if req.RestaurantID == nil {
+		if err := validateLocation(req.Restaurant.Name, &req.Restaurant); err != nil {
+			return 0, fmt.Errorf("restaurant: %w", err)
+		}
 	}
+	if req.CustomerID == nil {
+		if err := validateLocation(req.Customer.Name, &req.Customer); err != nil {
+			return 0, fmt.Errorf("customer: %w", err)
+		}
+	}
+	order.RestaurantID = req.RestaurantID
+	order.CustomerID = req.CustomerID
+
+	var orderId int64
+	err := s.uow.WithTx(ctx, func(repos repository.Repositories) error {
+		if req.RestaurantID != nil {
+			restaurant, err := repos.Restaurants.GetRestaurantByID(ctx, *req.RestaurantID)
+			if err != nil {
+				return err
+			}
+			order.ResLocationID = restaurant.LocationID
+		} else {
+			restaurant := req.Restaurant
+			restaurant.Type = orderModel.LocationTypeRestaurant
+			resID, err := repos.Orders.FindOrCreateLocation(ctx, &restaurant)
+			if err != nil {
+				return fmt.Errorf("failed to save restaurant: %w", err)
+			}
+			order.ResLocationID = resID
+		}
+
+		if req.CustomerID != nil {
+			customer, err := repos.Customers.GetCustomerByID(ctx, *req.CustomerID)
+			if err != nil {
+				return err
+			}
+			order.CusLocationID = customer.LocationID
+		} else {
+			customer := req.Customer
+			customer.Type = orderModel.LocationTypeCustomer
+			cusID, err := repos.Orders.FindOrCreateLocation(ctx, &customer)
+			if err != nil {
+				return fmt.Errorf("failed to save customer: %w", err)
+			}
+			order.CusLocationID = cusID
+		}
+
+		id, err := insertOrder(ctx, repos, &order)
+		if err != nil {
+			return err
+		}
+		orderId = id
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return orderId, nil
 }
 
-func (s *orderService) CreateLocation(loc *orderModel.Location) (int64, error) {
-	return s.repo.InsertLocation(loc)
+func (s *orderService) GetOrderByID(ctx context.Context, orderId int64) (*orderModel.Order, error) {
+	return s.repo.GetOrderByID(ctx, orderId)
 }
 
-func (s *orderService) GetLocationByID(id int64) (*orderModel.Location, error) {
-	return s.repo.GetLocationByID(id)
+// GetOrderDetails - The order with its restaurant and customer locations filled in
+func (s *orderService) GetOrderDetails(ctx context.Context, orderId int64) (*orderModel.OrderDetails, error) {
+	order, err := s.repo.GetOrderByID(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	locs, err := s.repo.GetLocationsByIDs(ctx, []int64{order.ResLocationID, order.CusLocationID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch order locations: %w", err)
+	}
+	byID := make(map[int64]orderModel.Location, len(locs))
+	for _, loc := range locs {
+		byID[int64(loc.ID)] = loc
+	}
+
+	restaurant, ok := byID[order.ResLocationID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrLocationNotFound, order.ResLocationID)
+	}
+	customer, ok := byID[order.CusLocationID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrLocationNotFound, order.CusLocationID)
+	}
+
+	return &orderModel.OrderDetails{
+		Order:      *order,
+		Restaurant: restaurant,
+		Customer:   customer,
+	}, nil
 }
 
-func (s *orderService) GetLocationsByIDs(ids []int64) ([]orderModel.Location, error) {
-	return s.repo.GetLocationsByIDs(ids)
+func (s *orderService) GetOrdersByIDs(ctx context.Context, ids []int64) ([]orderModel.Order, error) {
+	return s.repo.GetOrdersByIDs(ctx, ids)
 }
 
-func (s *orderService) CreateOrder(order *orderModel.Order) (int64, error) {
-	return s.repo.InsertOrder(order)
+// Page sizes of ListOrders
+const (
+	defaultOrderPageSize = 50
+	maxOrderPageSize     = 200
+)
+
+// OrderPage - One page of an order listing, NextCursor is empty on the last page
+type OrderPage struct {
+	Orders     []orderModel.Order `json:"orders"`
+	NextCursor string             `json:"nextCursor,omitempty"`
 }
 
-func (s *orderService) GetOrderByID(orderId int64) (*orderModel.Order, error) {
-	return s.repo.GetOrderByID(orderId)
+/*
+* ListOrders - Orders matching filter, a page at a time. cursor is the
+* NextCursor of the previous page, empty for the first one. A cursor is
+* only good for the sort it was made with.
+ */
+func (s *orderService) ListOrders(ctx context.Context, filter orderModel.OrderFilter, cursor string) (*OrderPage, error) {
+	if err := validateOrderFilter(&filter); err != nil {
+		return nil, err
+	}
+	if cursor != "" {
+		after, err := decodeOrderCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = after
+	}
+
+	// One extra row tells whether there is a next page
+	pageSize := filter.Limit
+	filter.Limit = pageSize + 1
+	orders, err := s.repo.ListOrders(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &OrderPage{Orders: orders}
+	if len(orders) > pageSize {
+		page.Orders = orders[:pageSize]
+		last := page.Orders[pageSize-1]
+		page.NextCursor = encodeOrderCursor(orderModel.OrderCursor{OrderID: int64(last.OrderID), CreatedAt: last.CreatedAt})
+	}
+	return page, nil
 }
 
-func (s *orderService) GetOrdersByIDs(ids []int64) ([]orderModel.Order, error) {
-	return s.repo.GetOrdersByIDs(ids)
+// validateOrderFilter - Checks filter and fills in the default sort and page size
+func validateOrderFilter(filter *orderModel.OrderFilter) error {
+	switch {
+	case filter.Limit == 0:
+		filter.Limit = defaultOrderPageSize
+	case filter.Limit < 0 || filter.Limit > maxOrderPageSize:
+		return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidOrderFilter, maxOrderPageSize)
+	}
+
+	switch filter.Sort {
+	case "":
+		filter.Sort = orderModel.OrderSortIDAsc
+	case orderModel.OrderSortIDAsc, orderModel.OrderSortIDDesc, orderModel.OrderSortCreatedAtAsc, orderModel.OrderSortCreatedAtDesc:
+	default:
+		return fmt.Errorf("%w: unknown sort %q", ErrInvalidOrderFilter, filter.Sort)
+	}
+
+	for _, status := range filter.Statuses {
+		if _, ok := orderStatusTransitions[status]; !ok {
+			return fmt.Errorf("%w: %q", ErrUnknownOrderStatus, status)
+		}
+	}
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && !filter.CreatedTo.After(*filter.CreatedFrom) {
+		return fmt.Errorf("%w: created_to must be after created_from", ErrInvalidOrderFilter)
+	}
+	if box := filter.CustomerBox; box != nil {
+		if box.MinLatitude < -90 || box.MaxLatitude > 90 || box.MinLongitude < -180 || box.MaxLongitude > 180 {
+			return fmt.Errorf("%w: bounding box out of range", ErrInvalidOrderFilter)
+		}
+		if box.MinLatitude > box.MaxLatitude || box.MinLongitude > box.MaxLongitude {
+			return fmt.Errorf("%w: bounding box minimum is above its maximum", ErrInvalidOrderFilter)
+		}
+	}
+	return nil
+}
+
+// encodeOrderCursor - Opaque to clients, base64 of the cursor's JSON
+func encodeOrderCursor(cursor orderModel.OrderCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeOrderCursor(cursor string) (*orderModel.OrderCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid cursor", ErrInvalidOrderFilter)
+	}
+	var after orderModel.OrderCursor
+	if err := json.Unmarshal(raw, &after); err != nil || after.OrderID <= 0 {
+		return nil, fmt.Errorf("%w: invalid cursor", ErrInvalidOrderFilter)
+	}
+	return &after, nil
 }
 
+// UpdateOrderStatus - Moves the order to status if the lifecycle allows it
+func (s *orderService) UpdateOrderStatus(ctx context.Context, orderId int64, status orderModel.OrderStatus) (*orderModel.Order, error) {
+	if _, ok := orderStatusTransitions[status]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownOrderStatus, status)
+	}
+
+	orders, err := s.repo.GetOrdersByIDs(ctx, []int64{orderId})
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, fmt.Errorf("%w: %d", ErrOrderNotFound, orderId)
+	}
+
+	order := orders[0]
+	if !canTransition(order.Status, status) {
+		return nil, fmt.Errorf("%w: %s -> %s", ErrInvalidStatusTransition, order.Status, status)
+	}
+
+	if err := s.repo.UpdateOrderStatus(ctx, orderId, order.Status, status); err != nil {
+		return nil, err
+	}
+
+	order.Status = status
+	return &order, nil
+}
+
+/*
+* ordersFound - Checks every id came back from the repository. Missing ids
+* are named in ErrOrderNotFound, a repeated id fails validation.
+ */
+func ordersFound(ids []int64, orders []orderModel.Order) error {
+	found := make(map[int64]bool, len(orders))
+	for _, order := range orders {
+		found[int64(order.OrderID)] = true
+	}
+
+	missing := make([]int64, 0)
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %v", ErrOrderNotFound, missing)
+	}
+	if len(orders) != len(ids) {
+		return fmt.Errorf("%w: order ids must not repeat", ErrInvalidOrder)
+	}
+	return nil
+}
+
+// GetOrderStatusHistory - Every status change of the order, starting with its creation
+func (s *orderService) GetOrderStatusHistory(ctx context.Context, orderId int64) ([]orderModel.OrderStatusChange, error) {
+	if _, err := s.repo.GetOrderByID(ctx, orderId); err != nil {
+		return nil, err
+	}
+	return s.repo.GetOrderStatusHistory(ctx, orderId)
+}
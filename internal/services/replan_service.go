@@ -0,0 +1,385 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/config"
+	"github.com/SHIVAMSINGH0101/go-demo/internal/events"
+	orderModel "github.com/SHIVAMSINGH0101/go-demo/internal/models"
+	"github.com/SHIVAMSINGH0101/go-demo/internal/repository"
+	"github.com/SHIVAMSINGH0101/go-demo/internal/utils"
+)
+
+// This is ReplanService layer
+// Compares riders' GPS pings with their stored plans and re-plans when they fall behind or leave the route
+type ReplanService interface {
+	QueuePosition(loc *orderModel.RiderLocation)
+	Run(ctx context.Context)
+	CheckPosition(ctx context.Context, loc *orderModel.RiderLocation) (*ReplanResult, error)
+	Replan(ctx context.Context, riderId int64, reason orderModel.ReplanReason) (*orderModel.RoutePlanVersion, error)
+	GetCurrentPlan(ctx context.Context, riderId int64) (*orderModel.RoutePlanVersion, error)
+	GetPlanHistory(ctx context.Context, riderId int64) ([]orderModel.RoutePlanVersion, error)
+}
+
+// ReplanResult - What a GPS ping showed against the rider's plan
+type ReplanResult struct {
+	utils.RouteDeviation
+	Replanned bool                         `json:"replanned"`
+	Reason    orderModel.ReplanReason      `json:"reason,omitempty"`
+	Plan      *orderModel.RoutePlanVersion `json:"plan,omitempty"`
+}
+
+// RouteChangedEvent - Published when a rider's plan gets a new version
+type RouteChangedEvent struct {
+	RiderID int64 `json:"riderId"`
+	Version int   `json:"version"`
+	// PreviousVersion is 0 for the rider's first plan
+	PreviousVersion int                          `json:"previousVersion"`
+	Reason          orderModel.ReplanReason      `json:"reason"`
+	OffRouteKm      float64                      `json:"offRouteKm"`
+	DelayMinutes    float64                      `json:"delayMinutes"`
+	Plan            *orderModel.RoutePlanVersion `json:"plan"`
+}
+
+type replanService struct {
+	orders        repository.OrderRepository
+	riders        repository.RiderRepository
+	plans         repository.RoutePlanRepository
+	routes        RouteService
+	broker        *events.Broker
+	estimator     utils.TravelTimeEstimator
+	speedProfiles *utils.SpeedProfiles
+	cfg           config.ReplanConfig
+
+	// pending holds the latest queued ping per rider, queue the riders waiting for a check
+	pendingMu sync.Mutex
+	pending   map[int64]*orderModel.RiderLocation
+	queue     chan int64
+}
+
+func NewReplanService(
+	orders repository.OrderRepository,
+	riders repository.RiderRepository,
+	plans repository.RoutePlanRepository,
+	routes RouteService,
+	broker *events.Broker,
+	estimator utils.TravelTimeEstimator,
+	speedProfiles *utils.SpeedProfiles,
+	cfg config.ReplanConfig,
+) ReplanService {
+	return &replanService{
+		orders:        orders,
+		riders:        riders,
+		plans:         plans,
+		routes:        routes,
+		broker:        broker,
+		estimator:     estimator,
+		speedProfiles: speedProfiles,
+		cfg:           cfg,
+		pending:       make(map[int64]*orderModel.RiderLocation),
+		queue:         make(chan int64, max(cfg.QueueSize, 1)),
+	}
+}
+
+/*
+* QueuePosition - Hands a stored GPS ping to the workers in Run, without waiting
+* for the check. A rider already waiting only has their ping replaced by the
+* newer one. When the queue is full the ping is dropped, the next one retries.
+ */
+func (s *replanService) QueuePosition(loc *orderModel.RiderLocation) {
+	if !s.cfg.Enabled {
+		return
+	}
+
+	s.pendingMu.Lock()
+	_, waiting := s.pending[loc.RiderID]
+	s.pending[loc.RiderID] = loc
+	s.pendingMu.Unlock()
+	if waiting {
+		return
+	}
+
+	select {
+	case s.queue <- loc.RiderID:
+	default:
+		s.pendingMu.Lock()
+		delete(s.pending, loc.RiderID)
+		s.pendingMu.Unlock()
+		log.Printf("replan queue full, dropped ping of rider %d", loc.RiderID)
+	}
+}
+
+// Run - Checks queued pings on Workers goroutines until ctx is done
+func (s *replanService) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for w := 0; w < max(s.cfg.Workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *replanService) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case riderId := <-s.queue:
+			s.pendingMu.Lock()
+			loc := s.pending[riderId]
+			delete(s.pending, riderId)
+			s.pendingMu.Unlock()
+
+			// A failed re-plan is retried on the rider's next ping
+			result, err := s.CheckPosition(ctx, loc)
+			if err != nil {
+				log.Printf("failed to check rider %d against plan, err %+v", riderId, err)
+			} else if result.Replanned {
+				log.Printf("re-planned rider %d, reason %s, version %d", riderId, result.Reason, result.Plan.Version)
+			}
+		}
+	}
+}
+
+/*
+* CheckPosition - Compares a GPS ping with the leg the rider should be on.
+* The rider is re-planned when their orders changed, when they are further
+* than MaxOffRouteKm from the leg or would reach the next stop more than
+* MaxDelayMinutes late. A rider with orders and no plan gets their first one.
+* A ping the repository ignored for a newer one is not checked, the newer
+* ping's own check covers the rider.
+ */
+func (s *replanService) CheckPosition(ctx context.Context, loc *orderModel.RiderLocation) (*ReplanResult, error) {
+	result := &ReplanResult{}
+	if !s.cfg.Enabled {
+		return result, nil
+	}
+
+	rider, err := s.riders.GetRiderByID(ctx, loc.RiderID)
+	if err != nil {
+		return nil, err
+	}
+	// Re-plans start from the stored position, which has to be this ping's
+	if rider.LastLocationAt == nil || rider.LastLatitude != loc.Latitude || rider.LastLongitude != loc.Longitude {
+		return result, nil
+	}
+	activeIDs, err := s.riders.GetActiveOrderIDs(ctx, rider.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rider orders: %w", err)
+	}
+
+	current, err := s.plans.GetLatestPlan(ctx, rider.ID)
+	if errors.Is(err, repository.ErrPlanNotFound) {
+		if len(activeIDs) == 0 {
+			return result, nil
+		}
+		return s.replan(ctx, rider.ID, orderModel.ReplanInitial, result, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch route plan: %w", err)
+	}
+
+	done, changed, err := s.progress(ctx, current, activeIDs)
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		return s.replan(ctx, rider.ID, orderModel.ReplanOrdersChanged, result, current)
+	}
+
+	next := 0
+	for next < len(current.Steps) && done(current.Steps[next]) {
+		next++
+	}
+	if next == len(current.Steps) {
+		return result, nil
+	}
+
+	from := orderModel.Location{Latitude: current.StartLatitude, Longitude: current.StartLongitude}
+	if next > 0 {
+		from = stepLocation(current.Steps[next-1])
+	}
+	speed, err := s.speedProfiles.ForVehicle(rider.VehicleType)
+	if err != nil {
+		return nil, err
+	}
+	position := orderModel.Location{Latitude: loc.Latitude, Longitude: loc.Longitude}
+	result.RouteDeviation = utils.MeasureDeviation(from, stepLocation(current.Steps[next]), position,
+		loc.RecordedAt, current.Steps[next].ETA, s.estimator, speed)
+
+	if time.Since(current.CreatedAt) < time.Duration(s.cfg.MinIntervalSeconds)*time.Second {
+		return result, nil
+	}
+	switch {
+	case result.OffRouteKm > s.cfg.MaxOffRouteKm:
+		return s.replan(ctx, rider.ID, orderModel.ReplanOffRoute, result, current)
+	case result.DelayMinutes > s.cfg.MaxDelayMinutes:
+		return s.replan(ctx, rider.ID, orderModel.ReplanDelay, result, current)
+	}
+	return result, nil
+}
+
+/*
+* progress - Tells which steps of the plan are done from the orders' status,
+* and whether the rider's orders changed since it was made. Orders leaving
+* the rider count as a change unless they were delivered.
+ */
+func (s *replanService) progress(ctx context.Context, plan *orderModel.RoutePlanVersion, activeIDs []int64) (func(orderModel.PlannedRouteStep) bool, bool, error) {
+	planned := make(map[int64]struct{})
+	for _, step := range plan.Steps {
+		if step.OrderID != 0 {
+			planned[int64(step.OrderID)] = struct{}{}
+		}
+	}
+
+	active := make(map[int64]struct{}, len(activeIDs))
+	changed := false
+	for _, id := range activeIDs {
+		active[id] = struct{}{}
+		if _, ok := planned[id]; !ok {
+			changed = true
+		}
+	}
+
+	ids := make([]int64, 0, len(planned))
+	for id := range planned {
+		ids = append(ids, id)
+	}
+	orders, err := s.orders.GetOrdersByIDs(ctx, ids)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to fetch orders: %w", err)
+	}
+	status := make(map[int]orderModel.OrderStatus, len(orders))
+	for _, order := range orders {
+		status[order.OrderID] = order.Status
+		if _, ok := active[int64(order.OrderID)]; !ok && order.Status != orderModel.OrderStatusDelivered {
+			changed = true
+		}
+	}
+
+	done := func(step orderModel.PlannedRouteStep) bool {
+		switch step.StopType {
+		case utils.StopPickup:
+			return status[step.OrderID] == orderModel.OrderStatusPickedUp || status[step.OrderID] == orderModel.OrderStatusDelivered
+		case utils.StopDrop:
+			return status[step.OrderID] == orderModel.OrderStatusDelivered
+		default:
+			return false
+		}
+	}
+	return done, changed, nil
+}
+
+/*
+* Replan - Solves the rider's route from their latest position and stores it
+* as the next version. ErrPlanVersionConflict when another re-plan stored a
+* version first.
+ */
+func (s *replanService) Replan(ctx context.Context, riderId int64, reason orderModel.ReplanReason) (*orderModel.RoutePlanVersion, error) {
+	current, err := s.plans.GetLatestPlan(ctx, riderId)
+	if err != nil && !errors.Is(err, repository.ErrPlanNotFound) {
+		return nil, fmt.Errorf("failed to fetch route plan: %w", err)
+	}
+
+	result, err := s.replan(ctx, riderId, reason, &ReplanResult{}, current)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Replanned {
+		return nil, repository.ErrPlanVersionConflict
+	}
+	return result.Plan, nil
+}
+
+/*
+* replan - Stores a fresh plan for the rider made from current, nil when they
+* have none, and publishes a route changed event. When another plan was
+* stored since current that one stands and result is returned unchanged.
+ */
+func (s *replanService) replan(ctx context.Context, riderId int64, reason orderModel.ReplanReason, result *ReplanResult, current *orderModel.RoutePlanVersion) (*ReplanResult, error) {
+	now := time.Now()
+	plan, err := s.routes.PlanRoute(ctx, RouteRequest{RiderID: riderId, Now: now})
+	if err != nil {
+		return nil, err
+	}
+
+	previous := 0
+	if current != nil {
+		previous = current.Version
+	}
+	version := planVersion(riderId, reason, now, plan.Response)
+	if _, err := s.plans.InsertPlan(ctx, version, previous); err != nil {
+		if errors.Is(err, repository.ErrPlanVersionConflict) {
+			return result, nil
+		}
+		return nil, fmt.Errorf("failed to store route plan: %w", err)
+	}
+
+	event := RouteChangedEvent{
+		RiderID:         riderId,
+		Version:         version.Version,
+		PreviousVersion: previous,
+		Reason:          reason,
+		OffRouteKm:      result.OffRouteKm,
+		DelayMinutes:    result.DelayMinutes,
+		Plan:            version,
+	}
+	s.broker.Publish(events.TypeRouteChanged, event)
+
+	result.Replanned = true
+	result.Reason = reason
+	result.Plan = version
+	return result, nil
+}
+
+// planVersion - The solved route with wall clock ETAs, ready to be stored
+func planVersion(riderId int64, reason orderModel.ReplanReason, now time.Time, route utils.BestRouteResponse) *orderModel.RoutePlanVersion {
+	steps := make([]orderModel.PlannedRouteStep, 0, len(route.Route))
+	for _, step := range route.Route {
+		steps = append(steps, orderModel.PlannedRouteStep{
+			StopType:   step.StopType,
+			OrderID:    step.OrderID,
+			LocationID: step.LocationID,
+			Name:       step.Step,
+			Latitude:   step.Location.Latitude,
+			Longitude:  step.Location.Longitude,
+			ETA:        now.Add(time.Duration(step.ETA * float64(time.Minute))),
+		})
+	}
+
+	return &orderModel.RoutePlanVersion{
+		RiderID:          riderId,
+		Reason:           reason,
+		PlannedAt:        now,
+		StartLatitude:    route.Start.Latitude,
+		StartLongitude:   route.Start.Longitude,
+		TotalTimeMinutes: route.TotalTime,
+		Steps:            steps,
+	}
+}
+
+func stepLocation(step orderModel.PlannedRouteStep) orderModel.Location {
+	return orderModel.Location{ID: step.LocationID, Name: step.Name, Latitude: step.Latitude, Longitude: step.Longitude}
+}
+
+func (s *replanService) GetCurrentPlan(ctx context.Context, riderId int64) (*orderModel.RoutePlanVersion, error) {
+	if _, err := s.riders.GetRiderByID(ctx, riderId); err != nil {
+		return nil, err
+	}
+	return s.plans.GetLatestPlan(ctx, riderId)
+}
+
+func (s *replanService) GetPlanHistory(ctx context.Context, riderId int64) ([]orderModel.RoutePlanVersion, error) {
+	if _, err := s.riders.GetRiderByID(ctx, riderId); err != nil {
+		return nil, err
+	}
+	return s.plans.ListPlans(ctx, riderId)
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/apperrors"
+	orderModel "github.com/SHIVAMSINGH0101/go-demo/internal/models"
+	"github.com/SHIVAMSINGH0101/go-demo/internal/repository"
+	"github.com/SHIVAMSINGH0101/go-demo/internal/utils"
+)
+
+// This is RiderService layer
+// Rider profile, shift, live location and order assignment logic
+type RiderService interface {
+	CreateRider(ctx context.Context, rider *orderModel.Rider) (int64, error)
+	GetRiderByID(ctx context.Context, id int64) (*orderModel.Rider, error)
+	UpdateShiftStatus(ctx context.Context, id int64, status orderModel.RiderShiftStatus) error
+	RecordLocation(ctx context.Context, loc *orderModel.RiderLocation) error
+
+	AssignOrders(ctx context.Context, riderId int64, orderIds []int64) error
+	UnassignOrder(ctx context.Context, riderId, orderId int64) error
+	GetActiveOrderIDs(ctx context.Context, riderId int64) ([]int64, error)
+}
+
+// ErrInvalidRider - Rider input failed validation
+var ErrInvalidRider = apperrors.New(apperrors.ErrValidation, "invalid rider")
+
+// maxPingClockSkew - How far ahead of the server clock a GPS ping's own time is trusted
+const maxPingClockSkew = time.Minute
+
+type riderService struct {
+	riders        repository.RiderRepository
+	orders        repository.OrderRepository
+	uow           repository.UnitOfWork
+	speedProfiles *utils.SpeedProfiles
+}
+
+func NewRiderService(
+	riders repository.RiderRepository,
+	orders repository.OrderRepository,
+	uow repository.UnitOfWork,
+	speedProfiles *utils.SpeedProfiles,
+) RiderService {
+	return &riderService{
+		riders:        riders,
+		orders:        orders,
+		uow:           uow,
+		speedProfiles: speedProfiles,
+	}
+}
+
+func (s *riderService) CreateRider(ctx context.Context, rider *orderModel.Rider) (int64, error) {
+	if rider.Name == "" {
+		return 0, fmt.Errorf("%w: name is required", ErrInvalidRider)
+	}
+	if rider.Capacity <= 0 {
+		return 0, fmt.Errorf("%w: capacity must be positive", ErrInvalidRider)
+	}
+	if rider.MaxWeightKg < 0 {
+		return 0, fmt.Errorf("%w: max weight can not be negative", ErrInvalidRider)
+	}
+	// Vehicle type picks the speed profile used for the rider's routes
+	if rider.VehicleType == "" {
+		rider.VehicleType = s.speedProfiles.DefaultVehicle
+	}
+	if _, err := s.speedProfiles.ForVehicle(rider.VehicleType); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidRider, err)
+	}
+	if rider.ShiftStatus == "" {
+		rider.ShiftStatus = orderModel.RiderShiftOff
+	}
+	if !validShiftStatus(rider.ShiftStatus) {
+		return 0, fmt.Errorf("%w: unknown shift status %q", ErrInvalidRider, rider.ShiftStatus)
+	}
+
+	return s.riders.InsertRider(ctx, rider)
+}
+
+func (s *riderService) GetRiderByID(ctx context.Context, id int64) (*orderModel.Rider, error) {
+	return s.riders.GetRiderByID(ctx, id)
+}
+
+func (s *riderService) UpdateShiftStatus(ctx context.Context, id int64, status orderModel.RiderShiftStatus) error {
+	if !validShiftStatus(status) {
+		return fmt.Errorf("%w: unknown shift status %q", ErrInvalidRider, status)
+	}
+	return s.riders.UpdateShiftStatus(ctx, id, status)
+}
+
+/*
+* RecordLocation - Stores a GPS ping, pings older than the stored one are ignored.
+* A ping stamped further ahead than maxPingClockSkew is stored at the server
+* time, otherwise a rider's fast clock would hide their next pings.
+ */
+func (s *riderService) RecordLocation(ctx context.Context, loc *orderModel.RiderLocation) error {
+	if loc.Latitude < -90 || loc.Latitude > 90 || loc.Longitude < -180 || loc.Longitude > 180 {
+		return fmt.Errorf("%w: coordinates out of range", ErrInvalidRider)
+	}
+	now := time.Now()
+	if loc.RecordedAt.IsZero() || loc.RecordedAt.After(now.Add(maxPingClockSkew)) {
+		loc.RecordedAt = now
+	}
+
+	if _, err := s.riders.GetRiderByID(ctx, loc.RiderID); err != nil {
+		return err
+	}
+	return s.riders.UpdateLocation(ctx, loc)
+}
+
+// AssignOrders - Hands open orders to the rider without going over the bag units the rider carries,
+// an order another rider has must be unassigned from them first
+func (s *riderService) AssignOrders(ctx context.Context, riderId int64, orderIds []int64) error {
+	// The rider stays locked from the capacity check to the insert, so two assignments can't both fill the bag
+	return s.uow.WithTx(ctx, func(repos repository.Repositories) error {
+		if err := repos.Riders.LockRider(ctx, riderId); err != nil {
+			return err
+		}
+		rider, err := repos.Riders.GetRiderByID(ctx, riderId)
+		if err != nil {
+			return err
+		}
+
+		orders, err := repos.Orders.GetOrdersByIDs(ctx, orderIds)
+		if err != nil {
+			return err
+		}
+		if err := ordersFound(orderIds, orders); err != nil {
+			return err
+		}
+		for _, order := range orders {
+			if order.Status == orderModel.OrderStatusDelivered || order.Status == orderModel.OrderStatusCancelled {
+				return fmt.Errorf("%w: order %d is %s", ErrInvalidRider, order.OrderID, order.Status)
+			}
+		}
+
+		active, err := repos.Riders.GetActiveOrderIDs(ctx, riderId)
+		if err != nil {
+			return err
+		}
+		requested := make(map[int64]struct{}, len(orderIds))
+		for _, id := range orderIds {
+			requested[id] = struct{}{}
+		}
+		carried := make([]int64, 0, len(active))
+		for _, id := range active {
+			if _, ok := requested[id]; !ok {
+				carried = append(carried, id)
+			}
+		}
+		carriedOrders, err := repos.Orders.GetOrdersByIDs(ctx, carried)
+		if err != nil {
+			return err
+		}
+
+		// Orders take bag units by size, the same units the route solver loads against capacity
+		units := utils.TotalBagUnits(carriedOrders)
+		for _, order := range orders {
+			units += utils.BagUnits(order)
+		}
+		if units > rider.Capacity {
+			return fmt.Errorf("%w: orders take %d bag units, rider capacity is %d", ErrInvalidRider, units, rider.Capacity)
+		}
+
+		return repos.Riders.AssignOrders(ctx, riderId, orderIds)
+	})
+}
+
+func (s *riderService) UnassignOrder(ctx context.Context, riderId, orderId int64) error {
+	if _, err := s.riders.GetRiderByID(ctx, riderId); err != nil {
+		return err
+	}
+	return s.riders.UnassignOrder(ctx, riderId, orderId)
+}
+
+func (s *riderService) GetActiveOrderIDs(ctx context.Context, riderId int64) ([]int64, error) {
+	if _, err := s.riders.GetRiderByID(ctx, riderId); err != nil {
+		return nil, err
+	}
+	return s.riders.GetActiveOrderIDs(ctx, riderId)
+}
+
+func validShiftStatus(status orderModel.RiderShiftStatus) bool {
+	switch status {
+	case orderModel.RiderShiftOff, orderModel.RiderShiftOn, orderModel.RiderShiftOnBreak:
+		return true
+	}
+	return false
+}
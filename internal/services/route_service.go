@@ -0,0 +1,576 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/apperrors"
+	"github.com/SHIVAMSINGH0101/go-demo/internal/config"
+	orderModel "github.com/SHIVAMSINGH0101/go-demo/internal/models"
+	"github.com/SHIVAMSINGH0101/go-demo/internal/repository"
+	"github.com/SHIVAMSINGH0101/go-demo/internal/utils"
+)
+
+// This is RouteService layer
+// Loads orders, locations and riders and runs the route solvers on them
+type RouteService interface {
+	GetBestRoute(ctx context.Context, req RouteRequest) (*utils.BestRouteResponse, error)
+	PlanRoute(ctx context.Context, req RouteRequest) (*utils.RoutePlan, error)
+	RankInsertions(ctx context.Context, req InsertionRequest) (*utils.InsertionResult, error)
+	PlanFleet(ctx context.Context, req FleetRequest) (*utils.FleetPlan, error)
+}
+
+// RouteRequest - Inputs of a best route computation
+type RouteRequest struct {
+	// RiderID, when set, fills Start, OrderIDs and Vehicle from the rider
+	RiderID  int64
+	Start    orderModel.Location
+	OrderIDs []int64
+	Vehicle  string
+	Strategy string
+	// Objective defaults to the configured one when empty
+	Objective string
+	Now       time.Time
+	// Capacity and MaxWeightKg limit the rider's load, 0 for no limit
+	Capacity    int
+	MaxWeightKg float64
+	// SoftWindows lets the route miss a deliver_before at a lateness penalty
+	SoftWindows bool
+	// Alternatives is how many runner-up routes to return besides the best
+	Alternatives int
+	// StartLocationID starts the route at a stored location, e.g. a hub, instead of Start
+	StartLocationID int64
+	// EndLocationID finishes the route at a stored location and RoundTrip back
+	// at the start. Without either the route ends at its last stop.
+	EndLocationID int64
+	RoundTrip     bool
+	// ViaLocationIDs are stored locations the route has to pass through, in any order
+	ViaLocationIDs []int64
+}
+
+/*
+* InsertionRequest - A rider's route in progress and an order to fit into it.
+* Route is the whole planned route, of which the first CompletedSteps are done.
+* Of the stops left, the first CommittedSteps can not be moved.
+* PickedUpOrderIDs are orders in the bag whose pickup is not part of Route.
+ */
+type InsertionRequest struct {
+	Start            orderModel.Location
+	Route            []utils.PlannedStop
+	CompletedSteps   int
+	CommittedSteps   int
+	PickedUpOrderIDs []int64
+	CandidateOrderID int64
+	Vehicle          string
+	Now              time.Time
+	// Capacity and MaxWeightKg limit the rider's load, 0 for no limit
+	Capacity    int
+	MaxWeightKg float64
+	// Limit caps the number of insertion options returned
+	Limit int
+}
+
+// FleetRequest - Orders to share out between riders and route for each of them
+type FleetRequest struct {
+	OrderIDs []int64
+	Riders   []FleetRiderRequest
+	// Objective defaults to the configured one when empty
+	Objective string
+	// FleetObjective is "sum" or "max" of the riders' objective values
+	FleetObjective string
+	Now            time.Time
+	SoftWindows    bool
+}
+
+/*
+* FleetRiderRequest - A rider of a fleet plan, either a stored rider or one
+* given only by position. Fields left empty are taken from the stored rider.
+ */
+type FleetRiderRequest struct {
+	RiderID     int64
+	Start       *orderModel.Location
+	Vehicle     string
+	Capacity    int
+	MaxWeightKg float64
+}
+
+// defaultInsertionLimit - Insertion options returned when the request has no limit
+const defaultInsertionLimit = 3
+
+// ErrInvalidRouteRequest - Route inputs failed validation
+var ErrInvalidRouteRequest = apperrors.New(apperrors.ErrValidation, "invalid route request")
+
+// ErrLocationNotFound - A start, end or via location of a route does not exist
+var ErrLocationNotFound = repository.ErrLocationNotFound
+
+type routeService struct {
+	orders        repository.OrderRepository
+	riders        repository.RiderRepository
+	cfg           config.RoutingConfig
+	estimator     utils.TravelTimeEstimator
+	speedProfiles *utils.SpeedProfiles
+}
+
+func NewRouteService(
+	orders repository.OrderRepository,
+	riders repository.RiderRepository,
+	cfg config.RoutingConfig,
+	estimator utils.TravelTimeEstimator,
+	speedProfiles *utils.SpeedProfiles,
+) RouteService {
+	return &routeService{
+		orders:        orders,
+		riders:        riders,
+		cfg:           cfg,
+		estimator:     estimator,
+		speedProfiles: speedProfiles,
+	}
+}
+
+func (s *routeService) GetBestRoute(ctx context.Context, req RouteRequest) (*utils.BestRouteResponse, error) {
+	plan, err := s.PlanRoute(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &plan.Response, nil
+}
+
+// PlanRoute - Same as GetBestRoute but keeps the plan so extra orders can be priced against it
+func (s *routeService) PlanRoute(ctx context.Context, req RouteRequest) (*utils.RoutePlan, error) {
+	if req.RiderID != 0 {
+		if err := s.fillFromRider(ctx, &req); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Capacity < 0 || req.MaxWeightKg < 0 {
+		return nil, fmt.Errorf("%w: capacity and max weight can not be negative", ErrInvalidRouteRequest)
+	}
+	if req.Alternatives < 0 || req.Alternatives > utils.MaxRouteAlternatives {
+		return nil, fmt.Errorf("%w: k must be between 0 and %d", ErrInvalidRouteRequest, utils.MaxRouteAlternatives)
+	}
+
+	if len(req.OrderIDs) > s.cfg.MaxOrders {
+		return nil, fmt.Errorf("%w: at most %d orders are supported", ErrInvalidRouteRequest, s.cfg.MaxOrders)
+	}
+	if req.RoundTrip && req.EndLocationID != 0 {
+		return nil, fmt.Errorf("%w: a round trip can not have an end location", ErrInvalidRouteRequest)
+	}
+	if len(req.ViaLocationIDs) > s.cfg.MaxOrders {
+		return nil, fmt.Errorf("%w: at most %d via locations are supported", ErrInvalidRouteRequest, s.cfg.MaxOrders)
+	}
+
+	speed, err := s.speedProfiles.ForVehicle(req.Vehicle)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRouteRequest, err)
+	}
+
+	// Every via location counts as an order towards the exact search limit
+	solver, err := utils.SelectRouteSolver(req.Strategy, len(req.OrderIDs)+len(req.ViaLocationIDs), s.cfg.ExactMaxOrders)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRouteRequest, err)
+	}
+
+	objective := req.Objective
+	if objective == "" {
+		objective = s.cfg.Objective
+	}
+	objective, err = utils.ValidateObjective(objective)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRouteRequest, err)
+	}
+
+	// Get Orders data
+	orders, err := s.orders.GetOrdersByIDs(ctx, req.OrderIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch orders: %w", err)
+	}
+	if err := ordersFound(req.OrderIDs, orders); err != nil {
+		return nil, err
+	}
+
+	for _, order := range orders {
+		if order.Status == orderModel.OrderStatusDelivered || order.Status == orderModel.OrderStatusCancelled {
+			return nil, fmt.Errorf("%w: order %d is %s", ErrInvalidRouteRequest, order.OrderID, order.Status)
+		}
+	}
+
+	locIDs := make([]int64, 0, 2*len(orders)+len(req.ViaLocationIDs)+2)
+	for _, order := range orders {
+		locIDs = append(locIDs, order.ResLocationID, order.CusLocationID)
+	}
+	locIDs = append(locIDs, req.ViaLocationIDs...)
+	if req.StartLocationID != 0 {
+		locIDs = append(locIDs, req.StartLocationID)
+	}
+	if req.EndLocationID != 0 {
+		locIDs = append(locIDs, req.EndLocationID)
+	}
+	// Get locations data for the locationIds in the orders and the route's path
+	locations, err := s.orders.GetLocationsByIDs(ctx, locIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch locations: %w", err)
+	}
+
+	start, end, waypoints, err := pathLocations(req, locations)
+	if err != nil {
+		return nil, err
+	}
+
+	now := req.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	// Returns the best possible route to cover all orders
+	return utils.PlanRoute(start, orders, locations, utils.RouteOptions{
+		Solver:       solver,
+		Estimator:    s.estimator,
+		Speed:        speed,
+		Now:          now,
+		Objective:    objective,
+		Capacity:     req.Capacity,
+		MaxWeightKg:  req.MaxWeightKg,
+		SoftWindows:  req.SoftWindows,
+		Alternatives: req.Alternatives,
+		End:          end,
+		Waypoints:    waypoints,
+	})
+}
+
+// pathLocations - Where the route starts and ends and the locations it has to pass through
+func pathLocations(req RouteRequest, locations []orderModel.Location) (orderModel.Location, *orderModel.Location, []orderModel.Location, error) {
+	byID := make(map[int64]orderModel.Location, len(locations))
+	for _, loc := range locations {
+		byID[int64(loc.ID)] = loc
+	}
+	lookup := func(id int64) (orderModel.Location, error) {
+		loc, ok := byID[id]
+		if !ok {
+			return loc, fmt.Errorf("%w: %d", ErrLocationNotFound, id)
+		}
+		return loc, nil
+	}
+
+	start := req.Start
+	if req.StartLocationID != 0 {
+		loc, err := lookup(req.StartLocationID)
+		if err != nil {
+			return start, nil, nil, err
+		}
+		start = loc
+	}
+
+	var end *orderModel.Location
+	switch {
+	case req.RoundTrip:
+		end = &start
+	case req.EndLocationID != 0:
+		loc, err := lookup(req.EndLocationID)
+		if err != nil {
+			return start, nil, nil, err
+		}
+		end = &loc
+	}
+
+	waypoints := make([]orderModel.Location, 0, len(req.ViaLocationIDs))
+	for _, id := range req.ViaLocationIDs {
+		loc, err := lookup(id)
+		if err != nil {
+			return start, nil, nil, err
+		}
+		waypoints = append(waypoints, loc)
+	}
+
+	return start, end, waypoints, nil
+}
+
+// fillFromRider - Routes the rider's assigned, undelivered orders from their latest position
+func (s *routeService) fillFromRider(ctx context.Context, req *RouteRequest) error {
+	rider, err := s.riders.GetRiderByID(ctx, req.RiderID)
+	if err != nil {
+		return err
+	}
+	if rider.LastLocationAt == nil {
+		return fmt.Errorf("%w: rider %d has no known location", ErrInvalidRouteRequest, rider.ID)
+	}
+
+	orderIDs, err := s.riders.GetActiveOrderIDs(ctx, rider.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch rider orders: %w", err)
+	}
+
+	req.Start = orderModel.Location{
+		Latitude:  rider.LastLatitude,
+		Longitude: rider.LastLongitude,
+	}
+	req.OrderIDs = orderIDs
+	if req.Vehicle == "" {
+		req.Vehicle = rider.VehicleType
+	}
+	if req.Capacity == 0 {
+		req.Capacity = rider.Capacity
+	}
+	if req.MaxWeightKg == 0 {
+		req.MaxWeightKg = rider.MaxWeightKg
+	}
+	return nil
+}
+
+// RankInsertions - Cheapest ways to add the candidate order to a route in progress
+func (s *routeService) RankInsertions(ctx context.Context, req InsertionRequest) (*utils.InsertionResult, error) {
+	if req.CandidateOrderID <= 0 {
+		return nil, fmt.Errorf("%w: candidate order id is required", ErrInvalidRouteRequest)
+	}
+	if req.CompletedSteps < 0 || req.CompletedSteps > len(req.Route) || req.CommittedSteps < 0 {
+		return nil, fmt.Errorf("%w: completed and committed steps must fit in the route", ErrInvalidRouteRequest)
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultInsertionLimit
+	}
+
+	// Completed steps tell which orders are in the bag and which are delivered
+	pickedUp := make(map[int64]bool)
+	for _, id := range req.PickedUpOrderIDs {
+		pickedUp[id] = true
+	}
+	delivered := make(map[int64]bool)
+	for _, stop := range req.Route[:req.CompletedSteps] {
+		if stop.IsPickup {
+			pickedUp[stop.OrderID] = true
+		} else {
+			delivered[stop.OrderID] = true
+		}
+	}
+
+	remaining := req.Route[req.CompletedSteps:]
+	orderIDs := make([]int64, 0)
+	seen := make(map[int64]struct{})
+	for _, stop := range remaining {
+		if delivered[stop.OrderID] {
+			return nil, fmt.Errorf("%w: order %d is already delivered", ErrInvalidRouteRequest, stop.OrderID)
+		}
+		if _, ok := seen[stop.OrderID]; !ok {
+			seen[stop.OrderID] = struct{}{}
+			orderIDs = append(orderIDs, stop.OrderID)
+		}
+	}
+	if _, ok := seen[req.CandidateOrderID]; ok || delivered[req.CandidateOrderID] {
+		return nil, fmt.Errorf("%w: order %d is already on the route", ErrInvalidRouteRequest, req.CandidateOrderID)
+	}
+	if len(orderIDs)+1 > s.cfg.MaxOrders {
+		return nil, fmt.Errorf("%w: at most %d orders are supported", ErrInvalidRouteRequest, s.cfg.MaxOrders)
+	}
+
+	speed, err := s.speedProfiles.ForVehicle(req.Vehicle)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRouteRequest, err)
+	}
+
+	allIDs := append(append([]int64(nil), orderIDs...), req.CandidateOrderID)
+	orders, err := s.orders.GetOrdersByIDs(ctx, allIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch orders: %w", err)
+	}
+	if err := ordersFound(allIDs, orders); err != nil {
+		return nil, err
+	}
+
+	ordersByID := make(map[int64]orderModel.Order, len(orders))
+	for _, order := range orders {
+		ordersByID[int64(order.OrderID)] = order
+	}
+
+	candidate := ordersByID[req.CandidateOrderID]
+	switch candidate.Status {
+	case orderModel.OrderStatusPickedUp, orderModel.OrderStatusDelivered, orderModel.OrderStatusCancelled:
+		return nil, fmt.Errorf("%w: order %d is %s", ErrInvalidRouteRequest, candidate.OrderID, candidate.Status)
+	}
+
+	// The route state in the request wins over the stored status
+	routeOrders := make([]orderModel.Order, 0, len(orderIDs))
+	for _, id := range orderIDs {
+		order := ordersByID[id]
+		if order.Status == orderModel.OrderStatusDelivered || order.Status == orderModel.OrderStatusCancelled {
+			return nil, fmt.Errorf("%w: order %d is %s", ErrInvalidRouteRequest, order.OrderID, order.Status)
+		}
+		if pickedUp[id] {
+			order.Status = orderModel.OrderStatusPickedUp
+		} else if order.Status == orderModel.OrderStatusPickedUp {
+			order.Status = orderModel.OrderStatusReady
+		}
+		routeOrders = append(routeOrders, order)
+	}
+
+	locIDs := make([]int64, 0, 2*len(orders))
+	for _, order := range orders {
+		locIDs = append(locIDs, order.ResLocationID, order.CusLocationID)
+	}
+	locations, err := s.orders.GetLocationsByIDs(ctx, locIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch locations: %w", err)
+	}
+
+	now := req.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	result, err := utils.RankInsertions(utils.ActiveRoute{
+		Start:     req.Start,
+		Orders:    routeOrders,
+		Stops:     remaining,
+		Committed: req.CommittedSteps,
+	}, candidate, locations, utils.RouteOptions{
+		Estimator:   s.estimator,
+		Speed:       speed,
+		Now:         now,
+		Objective:   s.cfg.Objective,
+		Capacity:    req.Capacity,
+		MaxWeightKg: req.MaxWeightKg,
+	}, limit)
+	if errors.Is(err, utils.ErrInvalidActiveRoute) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRouteRequest, err)
+	}
+	return result, err
+}
+
+// PlanFleet - Shares the orders out between the riders and routes each of them
+func (s *routeService) PlanFleet(ctx context.Context, req FleetRequest) (*utils.FleetPlan, error) {
+	if len(req.OrderIDs) == 0 || len(req.Riders) == 0 {
+		return nil, fmt.Errorf("%w: at least one order and one rider are required", ErrInvalidRouteRequest)
+	}
+	maxOrders := s.cfg.FleetMaxOrders
+	if maxOrders <= 0 || maxOrders > utils.MaxFleetOrders {
+		maxOrders = utils.MaxFleetOrders
+	}
+	if len(req.OrderIDs) > maxOrders {
+		return nil, fmt.Errorf("%w: at most %d orders are supported", ErrInvalidRouteRequest, maxOrders)
+	}
+	if len(req.Riders) > s.cfg.FleetMaxRiders {
+		return nil, fmt.Errorf("%w: at most %d riders are supported", ErrInvalidRouteRequest, s.cfg.FleetMaxRiders)
+	}
+	seenOrders := make(map[int64]struct{}, len(req.OrderIDs))
+	for _, id := range req.OrderIDs {
+		if _, ok := seenOrders[id]; ok {
+			return nil, fmt.Errorf("%w: order %d is listed twice", ErrInvalidRouteRequest, id)
+		}
+		seenOrders[id] = struct{}{}
+	}
+
+	objective := req.Objective
+	if objective == "" {
+		objective = s.cfg.Objective
+	}
+	objective, err := utils.ValidateObjective(objective)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRouteRequest, err)
+	}
+	fleetObjective, err := utils.ValidateFleetObjective(req.FleetObjective)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRouteRequest, err)
+	}
+
+	riders := make([]utils.FleetRider, 0, len(req.Riders))
+	seenRiders := make(map[int64]struct{})
+	for _, r := range req.Riders {
+		if r.RiderID != 0 {
+			if _, ok := seenRiders[r.RiderID]; ok {
+				return nil, fmt.Errorf("%w: rider %d is listed twice", ErrInvalidRouteRequest, r.RiderID)
+			}
+			seenRiders[r.RiderID] = struct{}{}
+		}
+		rider, err := s.fleetRider(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		riders = append(riders, rider)
+	}
+
+	orders, err := s.orders.GetOrdersByIDs(ctx, req.OrderIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch orders: %w", err)
+	}
+	if err := ordersFound(req.OrderIDs, orders); err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		switch order.Status {
+		case orderModel.OrderStatusPickedUp, orderModel.OrderStatusDelivered, orderModel.OrderStatusCancelled:
+			return nil, fmt.Errorf("%w: order %d is %s", ErrInvalidRouteRequest, order.OrderID, order.Status)
+		}
+	}
+
+	locIDs := make([]int64, 0, 2*len(orders))
+	for _, order := range orders {
+		locIDs = append(locIDs, order.ResLocationID, order.CusLocationID)
+	}
+	locations, err := s.orders.GetLocationsByIDs(ctx, locIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch locations: %w", err)
+	}
+
+	now := req.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	return utils.PlanFleet(riders, orders, locations, utils.FleetOptions{
+		Estimator:      s.estimator,
+		Now:            now,
+		Objective:      objective,
+		FleetObjective: fleetObjective,
+		SoftWindows:    req.SoftWindows,
+		ExactMaxOrders: s.cfg.ExactMaxOrders,
+	})
+}
+
+// fleetRider - Fills a fleet rider from the stored rider, the request's fields win
+func (s *routeService) fleetRider(ctx context.Context, req FleetRiderRequest) (utils.FleetRider, error) {
+	rider := utils.FleetRider{
+		RiderID:     req.RiderID,
+		Capacity:    req.Capacity,
+		MaxWeightKg: req.MaxWeightKg,
+	}
+	if req.Capacity < 0 || req.MaxWeightKg < 0 {
+		return rider, fmt.Errorf("%w: capacity and max weight can not be negative", ErrInvalidRouteRequest)
+	}
+
+	vehicle := req.Vehicle
+	if req.RiderID != 0 {
+		stored, err := s.riders.GetRiderByID(ctx, req.RiderID)
+		if err != nil {
+			return rider, err
+		}
+		if req.Start == nil {
+			if stored.LastLocationAt == nil {
+				return rider, fmt.Errorf("%w: rider %d has no known location", ErrInvalidRouteRequest, stored.ID)
+			}
+			rider.Start = orderModel.Location{Latitude: stored.LastLatitude, Longitude: stored.LastLongitude}
+		}
+		if vehicle == "" {
+			vehicle = stored.VehicleType
+		}
+		if rider.Capacity == 0 {
+			rider.Capacity = stored.Capacity
+		}
+		if rider.MaxWeightKg == 0 {
+			rider.MaxWeightKg = stored.MaxWeightKg
+		}
+	} else if req.Start == nil {
+		return rider, fmt.Errorf("%w: a rider needs a rider id or a position", ErrInvalidRouteRequest)
+	}
+	if req.Start != nil {
+		rider.Start = *req.Start
+	}
+
+	speed, err := s.speedProfiles.ForVehicle(vehicle)
+	if err != nil {
+		return rider, fmt.Errorf("%w: %v", ErrInvalidRouteRequest, err)
+	}
+	rider.Speed = speed
+	return rider, nil
+}
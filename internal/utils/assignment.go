@@ -0,0 +1,117 @@
+package utils
+
+import "math"
+
+/*
+* HungarianAssignment - Minimum cost matching of rows to columns.
+* Returns the column given to each row, or -1 when the row gets nothing
+* (more rows than columns, or only +Inf costs left for it).
+ */
+func HungarianAssignment(cost [][]float64) []int {
+	rows := len(cost)
+	if rows == 0 {
+		return []int{}
+	}
+	cols := len(cost[0])
+
+	// The algorithm below needs rows <= cols, solve the transpose otherwise
+	if rows > cols {
+		transposed := make([][]float64, cols)
+		for j := range transposed {
+			transposed[j] = make([]float64, rows)
+			for i := range cost {
+				transposed[j][i] = cost[i][j]
+			}
+		}
+
+		result := make([]int, rows)
+		for i := range result {
+			result[i] = -1
+		}
+		for j, i := range HungarianAssignment(transposed) {
+			if i >= 0 {
+				result[i] = j
+			}
+		}
+		return result
+	}
+
+	// +Inf would break the potentials, use a cost larger than any real one
+	big := 1.0
+	for _, row := range cost {
+		for _, c := range row {
+			if !math.IsInf(c, 1) {
+				big += math.Abs(c)
+			}
+		}
+	}
+	at := func(i, j int) float64 {
+		if math.IsInf(cost[i][j], 1) {
+			return big
+		}
+		return cost[i][j]
+	}
+
+	// Potentials u (rows) and v (cols), p[j] is the row matched to column j, all 1-indexed
+	u := make([]float64, rows+1)
+	v := make([]float64, cols+1)
+	p := make([]int, cols+1)
+	way := make([]int, cols+1)
+
+	for i := 1; i <= rows; i++ {
+		p[0] = i
+		j0 := 0
+		minv := make([]float64, cols+1)
+		used := make([]bool, cols+1)
+		for j := range minv {
+			minv[j] = math.MaxFloat64
+		}
+
+		for p[j0] != 0 {
+			used[j0] = true
+			i0 := p[j0]
+			delta := math.MaxFloat64
+			j1 := 0
+			for j := 1; j <= cols; j++ {
+				if used[j] {
+					continue
+				}
+				cur := at(i0-1, j-1) - u[i0] - v[j]
+				if cur < minv[j] {
+					minv[j] = cur
+					way[j] = j0
+				}
+				if minv[j] < delta {
+					delta = minv[j]
+					j1 = j
+				}
+			}
+			for j := 0; j <= cols; j++ {
+				if used[j] {
+					u[p[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			j0 = j1
+		}
+
+		for j0 != 0 {
+			j1 := way[j0]
+			p[j0] = p[j1]
+			j0 = j1
+		}
+	}
+
+	result := make([]int, rows)
+	for i := range result {
+		result[i] = -1
+	}
+	for j := 1; j <= cols; j++ {
+		if p[j] != 0 && !math.IsInf(cost[p[j]-1][j-1], 1) {
+			result[p[j]-1] = j - 1
+		}
+	}
+	return result
+}
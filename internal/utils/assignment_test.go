@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// bestMatching - Fewest +Inf pairs, then the lowest cost, over every full matching of rows to columns
+func bestMatching(cost [][]float64) (infPairs int, total float64) {
+	rows, cols := len(cost), len(cost[0])
+	size := min(rows, cols)
+	infPairs, total = size+1, math.Inf(1)
+
+	usedCols := make([]bool, cols)
+	var search func(row, matched, inf int, sum float64)
+	search = func(row, matched, inf int, sum float64) {
+		if matched == size {
+			if inf < infPairs || (inf == infPairs && sum < total) {
+				infPairs, total = inf, sum
+			}
+			return
+		}
+		if row == rows {
+			return
+		}
+		// Rows beyond the columns may go without one
+		if rows-row > size-matched {
+			search(row+1, matched, inf, sum)
+		}
+		for j := 0; j < cols; j++ {
+			if usedCols[j] {
+				continue
+			}
+			usedCols[j] = true
+			if math.IsInf(cost[row][j], 1) {
+				search(row+1, matched+1, inf+1, sum)
+			} else {
+				search(row+1, matched+1, inf, sum+cost[row][j])
+			}
+			usedCols[j] = false
+		}
+	}
+	search(0, 0, 0, 0)
+	return infPairs, total
+}
+
+func TestHungarianAssignmentMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(8))
+	for instance := 0; instance < 300; instance++ {
+		rows, cols := 1+rng.Intn(5), 1+rng.Intn(5)
+		cost := make([][]float64, rows)
+		for i := range cost {
+			cost[i] = make([]float64, cols)
+			for j := range cost[i] {
+				cost[i][j] = rng.Float64() * 50
+				if rng.Intn(4) == 0 {
+					cost[i][j] = math.Inf(1)
+				}
+			}
+		}
+
+		got := HungarianAssignment(cost)
+		if len(got) != rows {
+			t.Fatalf("instance %d: %d results for %d rows", instance, len(got), rows)
+		}
+		seen := make(map[int]bool)
+		matched, total := 0, 0.0
+		for i, j := range got {
+			if j < 0 {
+				continue
+			}
+			if seen[j] {
+				t.Fatalf("instance %d: column %d given twice in %v", instance, j, got)
+			}
+			if math.IsInf(cost[i][j], 1) {
+				t.Fatalf("instance %d: row %d given column %d at +Inf", instance, i, j)
+			}
+			seen[j] = true
+			matched++
+			total += cost[i][j]
+		}
+
+		infPairs, want := bestMatching(cost)
+		if matched != min(rows, cols)-infPairs || math.Abs(total-want) > 1e-6 {
+			t.Errorf("instance %d: %d pairs costing %.6f, want %d costing %.6f",
+				instance, matched, total, min(rows, cols)-infPairs, want)
+		}
+	}
+}
+
+// Taking each row's cheapest column in turn costs 101 here
+func TestHungarianAssignmentBeatsGreedy(t *testing.T) {
+	got := HungarianAssignment([][]float64{
+		{1, 2},
+		{2, 100},
+	})
+	if len(got) != 2 || got[0] != 1 || got[1] != 0 {
+		t.Errorf("HungarianAssignment = %v, want [1 0]", got)
+	}
+}
+
+func TestHungarianAssignmentEdgeCases(t *testing.T) {
+	if got := HungarianAssignment(nil); len(got) != 0 {
+		t.Errorf("no rows = %v, want none", got)
+	}
+
+	// Three riders for one order, only the cheapest gets it
+	got := HungarianAssignment([][]float64{{5}, {3}, {9}})
+	if got[0] != -1 || got[1] != 0 || got[2] != -1 {
+		t.Errorf("more rows than columns = %v, want [-1 0 -1]", got)
+	}
+
+	inf := math.Inf(1)
+	got = HungarianAssignment([][]float64{{inf, inf}, {4, inf}})
+	if got[0] != -1 || got[1] != 0 {
+		t.Errorf("row with only +Inf = %v, want [-1 0]", got)
+	}
+}
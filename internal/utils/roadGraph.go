@@ -0,0 +1,291 @@
+package utils
+
+import (
+	"container/heap"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/models"
+)
+
+// gridCellDegrees - Size of the lat/lon buckets used to snap points to nodes
+const gridCellDegrees = 0.01
+
+/*
+* RoadGraph - Travel time estimator over a road network extract.
+* A point is snapped to its nearest node, reaching that node is costed as a
+* straight line at the default speed, and node to node time comes from A*
+* over the edges. When no path exists the haversine estimate is returned.
+ */
+type RoadGraph struct {
+	nodes        []roadNode
+	adj          [][]roadEdge
+	grid         map[gridCell][]int
+	maxSpeedKmph float64
+	access       HaversineEstimator
+}
+
+type roadNode struct {
+	lat float64
+	lon float64
+}
+
+type roadEdge struct {
+	to      int
+	minutes float64
+	km      float64
+}
+
+type gridCell struct {
+	lat int
+	lon int
+}
+
+/*
+* LoadRoadGraph - Reads an OSM-derived graph from two CSV files with headers.
+* nodes: id,latitude,longitude
+* edges: from_id,to_id,distance_km,speed_kmph[,oneway]
+* Edges are two-way unless oneway is "1" or "true". PBF extracts are not
+* read, they have to be converted to these files first.
+ */
+func LoadRoadGraph(nodesPath, edgesPath string) (*RoadGraph, error) {
+	g := &RoadGraph{
+		grid:   make(map[gridCell][]int),
+		access: HaversineEstimator{SpeedKmph: defaultSpeedKmph},
+	}
+
+	nodeIndex := make(map[string]int)
+	err := readCSV(nodesPath, 3, func(rec []string) error {
+		lat, err := strconv.ParseFloat(rec[1], 64)
+		if err != nil {
+			return fmt.Errorf("invalid latitude %q: %w", rec[1], err)
+		}
+		lon, err := strconv.ParseFloat(rec[2], 64)
+		if err != nil {
+			return fmt.Errorf("invalid longitude %q: %w", rec[2], err)
+		}
+
+		idx := len(g.nodes)
+		nodeIndex[rec[0]] = idx
+		g.nodes = append(g.nodes, roadNode{lat: lat, lon: lon})
+		cell := cellOf(lat, lon)
+		g.grid[cell] = append(g.grid[cell], idx)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load road nodes: %w", err)
+	}
+	if len(g.nodes) == 0 {
+		return nil, fmt.Errorf("road graph %s has no nodes", nodesPath)
+	}
+
+	g.adj = make([][]roadEdge, len(g.nodes))
+	err = readCSV(edgesPath, 4, func(rec []string) error {
+		from, ok := nodeIndex[rec[0]]
+		if !ok {
+			return fmt.Errorf("unknown node %q", rec[0])
+		}
+		to, ok := nodeIndex[rec[1]]
+		if !ok {
+			return fmt.Errorf("unknown node %q", rec[1])
+		}
+		distKm, err := strconv.ParseFloat(rec[2], 64)
+		if err != nil {
+			return fmt.Errorf("invalid distance %q: %w", rec[2], err)
+		}
+		speed, err := strconv.ParseFloat(rec[3], 64)
+		if err != nil || speed <= 0 {
+			return fmt.Errorf("invalid speed %q", rec[3])
+		}
+
+		minutes := (distKm / speed) * 60
+		g.adj[from] = append(g.adj[from], roadEdge{to: to, minutes: minutes, km: distKm})
+		oneway := len(rec) > 4 && (rec[4] == "1" || strings.EqualFold(rec[4], "true"))
+		if !oneway {
+			g.adj[to] = append(g.adj[to], roadEdge{to: from, minutes: minutes, km: distKm})
+		}
+		g.maxSpeedKmph = math.Max(g.maxSpeedKmph, speed)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load road edges: %w", err)
+	}
+	// The A* heuristic divides by the fastest edge speed
+	if g.maxSpeedKmph == 0 {
+		return nil, fmt.Errorf("road graph %s has no edges", edgesPath)
+	}
+
+	return g, nil
+}
+
+// readCSV - Calls fn for every record after the header row
+func readCSV(path string, minFields int, fn func(rec []string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	if _, err := reader.Read(); err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+
+	for line := 2; ; line++ {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(rec) < minFields {
+			return fmt.Errorf("line %d: expected at least %d fields", line, minFields)
+		}
+		if err := fn(rec); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+}
+
+// EstimateLeg - Km are those of the fastest path, so a speed profile rides the roads the graph picked
+func (g *RoadGraph) EstimateLeg(from, to models.Location) Leg {
+	src := g.nearestNode(from.Latitude, from.Longitude)
+	dst := g.nearestNode(to.Latitude, to.Longitude)
+	if src == dst {
+		return g.access.EstimateLeg(from, to)
+	}
+
+	road, ok := g.shortestPath(src, dst)
+	if !ok {
+		return g.access.EstimateLeg(from, to)
+	}
+
+	in := g.access.EstimateLeg(from, g.location(src))
+	out := g.access.EstimateLeg(g.location(dst), to)
+	return Leg{
+		Minutes: in.Minutes + road.Minutes + out.Minutes,
+		Km:      in.Km + road.Km + out.Km,
+	}
+}
+
+func (g *RoadGraph) location(node int) models.Location {
+	return models.Location{Latitude: g.nodes[node].lat, Longitude: g.nodes[node].lon}
+}
+
+func cellOf(lat, lon float64) gridCell {
+	return gridCell{
+		lat: int(math.Floor(lat / gridCellDegrees)),
+		lon: int(math.Floor(lon / gridCellDegrees)),
+	}
+}
+
+// nearestNode - Searches grid rings around the point until a node is found, plus one more ring
+func (g *RoadGraph) nearestNode(lat, lon float64) int {
+	center := cellOf(lat, lon)
+	best := -1
+	bestDist := math.MaxFloat64
+	lastRing := -1
+
+	for ring := 0; lastRing < 0 || ring <= lastRing; ring++ {
+		for dLat := -ring; dLat <= ring; dLat++ {
+			for dLon := -ring; dLon <= ring; dLon++ {
+				if max(abs(dLat), abs(dLon)) != ring {
+					continue
+				}
+				for _, idx := range g.grid[gridCell{lat: center.lat + dLat, lon: center.lon + dLon}] {
+					dist := haversine(lat, lon, g.nodes[idx].lat, g.nodes[idx].lon)
+					if dist < bestDist {
+						best = idx
+						bestDist = dist
+					}
+				}
+			}
+		}
+		if best >= 0 && lastRing < 0 {
+			lastRing = ring + 1
+		}
+		// Far away from every node, fall back to a full scan
+		if best < 0 && ring > 100 {
+			for idx, node := range g.nodes {
+				dist := haversine(lat, lon, node.lat, node.lon)
+				if dist < bestDist {
+					best = idx
+					bestDist = dist
+				}
+			}
+			return best
+		}
+	}
+
+	return best
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// shortestPath - A* from src to dst on minutes, straight line at the fastest edge speed is the heuristic
+func (g *RoadGraph) shortestPath(src, dst int) (Leg, bool) {
+	heuristic := func(node int) float64 {
+		dist := haversine(g.nodes[node].lat, g.nodes[node].lon, g.nodes[dst].lat, g.nodes[dst].lon)
+		return (dist / g.maxSpeedKmph) * 60
+	}
+
+	best := map[int]float64{src: 0}
+	km := map[int]float64{src: 0}
+	queue := &nodeQueue{{node: src, priority: heuristic(src)}}
+
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(nodeQueueItem)
+		if item.node == dst {
+			return Leg{Minutes: best[dst], Km: km[dst]}, true
+		}
+		current := best[item.node]
+		if item.priority-heuristic(item.node) > current+1e-9 {
+			continue // stale entry
+		}
+
+		for _, edge := range g.adj[item.node] {
+			next := current + edge.minutes
+			if known, ok := best[edge.to]; ok && known <= next {
+				continue
+			}
+			best[edge.to] = next
+			km[edge.to] = km[item.node] + edge.km
+			heap.Push(queue, nodeQueueItem{node: edge.to, priority: next + heuristic(edge.to)})
+		}
+	}
+
+	return Leg{}, false
+}
+
+type nodeQueueItem struct {
+	node     int
+	priority float64
+}
+
+// nodeQueue - Min heap on priority for A*
+type nodeQueue []nodeQueueItem
+
+func (q nodeQueue) Len() int            { return len(q) }
+func (q nodeQueue) Less(i, j int) bool  { return q[i].priority < q[j].priority }
+func (q nodeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *nodeQueue) Push(x interface{}) { *q = append(*q, x.(nodeQueueItem)) }
+func (q *nodeQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeRoadGraph(t *testing.T, nodes, edges string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	nodesPath := filepath.Join(dir, "nodes.csv")
+	edgesPath := filepath.Join(dir, "edges.csv")
+	if err := os.WriteFile(nodesPath, []byte(nodes), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(edgesPath, []byte(edges), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return nodesPath, edgesPath
+}
+
+// a, b and c form a triangle where the two short edges beat the fast direct one, d is only reached one way
+const testRoadNodes = `id,latitude,longitude
+a,12.9000,77.6000
+b,12.9100,77.6000
+c,12.9100,77.6100
+d,12.9500,77.7000
+`
+
+const testRoadEdges = `from_id,to_id,distance_km,speed_kmph,oneway
+a,b,1.1,30
+b,c,1.1,30
+a,c,5,60
+c,d,10,30,true
+`
+
+func testRoadGraph(t *testing.T) *RoadGraph {
+	t.Helper()
+	g, err := LoadRoadGraph(writeRoadGraph(t, testRoadNodes, testRoadEdges))
+	if err != nil {
+		t.Fatalf("LoadRoadGraph: %v", err)
+	}
+	return g
+}
+
+func TestLoadRoadGraph(t *testing.T) {
+	g := testRoadGraph(t)
+
+	if len(g.nodes) != 4 {
+		t.Fatalf("loaded %d nodes, want 4", len(g.nodes))
+	}
+	// c-d is one way, the other edges are stored both ways
+	if got := len(g.adj[2]); got != 3 {
+		t.Errorf("node c has %d edges, want 3", got)
+	}
+	if got := len(g.adj[3]); got != 0 {
+		t.Errorf("node d has %d edges, want 0", got)
+	}
+	if g.maxSpeedKmph != 60 {
+		t.Errorf("max speed = %v, want 60", g.maxSpeedKmph)
+	}
+}
+
+func TestLoadRoadGraphErrors(t *testing.T) {
+	cases := map[string]struct{ nodes, edges string }{
+		"no nodes":     {"id,latitude,longitude\n", testRoadEdges},
+		"no edges":     {testRoadNodes, "from_id,to_id,distance_km,speed_kmph\n"},
+		"unknown node": {testRoadNodes, "from_id,to_id,distance_km,speed_kmph\na,z,1,30\n"},
+		"zero speed":   {testRoadNodes, "from_id,to_id,distance_km,speed_kmph\na,b,1,0\n"},
+		"bad latitude": {"id,latitude,longitude\na,north,77.6\n", testRoadEdges},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadRoadGraph(writeRoadGraph(t, tc.nodes, tc.edges)); err == nil {
+				t.Error("LoadRoadGraph succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestNearestNode(t *testing.T) {
+	g := testRoadGraph(t)
+
+	if got := g.nearestNode(12.9098, 77.6003); got != 1 {
+		t.Errorf("nearest node next to b = %d, want 1", got)
+	}
+	// d is more than 100 grid rings away, only the full scan finds it
+	if got := g.nearestNode(14.5, 79.0); got != 3 {
+		t.Errorf("nearest node far from the graph = %d, want 3", got)
+	}
+}
+
+// The point's own cell has a node, but the closest one is across the cell border
+func TestNearestNodeAcrossCells(t *testing.T) {
+	nodes := `id,latitude,longitude
+inside,12.9905,77.6050
+across,13.0001,77.6050
+`
+	g, err := LoadRoadGraph(writeRoadGraph(t, nodes, "from_id,to_id,distance_km,speed_kmph\ninside,across,1.1,30\n"))
+	if err != nil {
+		t.Fatalf("LoadRoadGraph: %v", err)
+	}
+	if got := g.nearestNode(12.9999, 77.6050); got != 1 {
+		t.Errorf("nearest node = %d, want the one across the border", got)
+	}
+}
+
+func TestShortestPath(t *testing.T) {
+	g := testRoadGraph(t)
+
+	// a-b-c takes 4.4 minutes, the direct edge 5
+	leg, ok := g.shortestPath(0, 2)
+	if !ok {
+		t.Fatal("no path from a to c")
+	}
+	if math.Abs(leg.Minutes-4.4) > 1e-9 || math.Abs(leg.Km-2.2) > 1e-9 {
+		t.Errorf("a to c = %.2f minutes over %.2f km, want 4.40 over 2.20", leg.Minutes, leg.Km)
+	}
+
+	if leg, ok := g.shortestPath(0, 3); !ok || math.Abs(leg.Minutes-24.4) > 1e-9 {
+		t.Errorf("a to d = %.2f minutes, %v, want 24.40", leg.Minutes, ok)
+	}
+	if _, ok := g.shortestPath(3, 0); ok {
+		t.Error("found a path from d against the one way edge")
+	}
+}
+
+// Without a road path the leg falls back to the straight line
+func TestRoadGraphEstimateLegFallback(t *testing.T) {
+	g := testRoadGraph(t)
+	from := g.location(3)
+	to := g.location(0)
+
+	if got, want := g.EstimateLeg(from, to), g.access.EstimateLeg(from, to); got != want {
+		t.Errorf("EstimateLeg = %+v, want the haversine %+v", got, want)
+	}
+}
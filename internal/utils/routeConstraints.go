@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/models"
+)
+
+/*
+* InfeasibleRouteError - Why no visiting sequence fits the rider's capacity,
+* the orders' in-bag limits and delivery windows. errors.Is matches it to ErrNoFeasibleRoute.
+ */
+type InfeasibleRouteError struct {
+	Reasons []string
+}
+
+func (e *InfeasibleRouteError) Error() string {
+	return ErrNoFeasibleRoute.Error() + ": " + strings.Join(e.Reasons, "; ")
+}
+
+func (e *InfeasibleRouteError) Is(target error) bool {
+	return target == ErrNoFeasibleRoute
+}
+
+// BagUnits - Bag units the order takes, orders without a size take 1
+func BagUnits(order models.Order) int {
+	if order.Size <= 0 {
+		return 1
+	}
+	return order.Size
+}
+
+// TotalBagUnits - Bag units the orders take together
+func TotalBagUnits(orders []models.Order) int {
+	total := 0
+	for _, order := range orders {
+		total += BagUnits(order)
+	}
+	return total
+}
+
+// step - Visits stop i if the route constraints allow it
+func (p *RouteProblem) step(st routeState, i int) (routeState, bool) {
+	if !p.canVisit(st, i) {
+		return st, false
+	}
+	next, _ := p.advance(st, i)
+	if next.elapsed > p.stops[i].WindowClose+costEpsilon {
+		return st, false
+	}
+	if p.bagLimits && !p.withinBagLimits(next, i) {
+		return st, false
+	}
+	return next, true
+}
+
+// fitsInBag - Whether picking up stop i keeps the load within the rider's capacity
+func (p *RouteProblem) fitsInBag(st routeState, i int) bool {
+	stop := p.stops[i]
+	if p.capacity > 0 && st.load+stop.Size > p.capacity {
+		return false
+	}
+	if p.maxWeightKg > 0 && st.weightKg+stop.WeightKg > p.maxWeightKg+costEpsilon {
+		return false
+	}
+	return true
+}
+
+// withinBagLimits - No order in the bag, or just dropped at stop i, is past its in-bag limit
+func (p *RouteProblem) withinBagLimits(st routeState, i int) bool {
+	for o, deadline := range st.bagDeadlines {
+		if math.IsInf(deadline, 1) {
+			continue
+		}
+		drop := 2*o + 1
+		if st.visited&(1<<uint(drop)) != 0 && drop != i {
+			continue
+		}
+		if st.elapsed > deadline+costEpsilon {
+			return false
+		}
+	}
+	return true
+}
+
+// laterBagDeadlines - Every order in a's bag may stay at least as long as in b's
+func laterBagDeadlines(a, b routeState) bool {
+	for o := range a.bagDeadlines {
+		if a.bagDeadlines[o] < b.bagDeadlines[o] {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+* explainInfeasible - Lists the orders that can not fit the constraints on
+* their own. When none is found the constraints only clash together, or the
+* heuristic that ran missed a route an exhaustive solver could find.
+ */
+func (p *RouteProblem) explainInfeasible(solver string, exhaustive bool) error {
+	reasons := make([]string, 0)
+
+	if p.capacity > 0 && p.origin.load > p.capacity {
+		reasons = append(reasons, fmt.Sprintf("orders already picked up take %d bag units, the rider carries %d", p.origin.load, p.capacity))
+	}
+	if p.maxWeightKg > 0 && p.origin.weightKg > p.maxWeightKg+costEpsilon {
+		reasons = append(reasons, fmt.Sprintf("orders already picked up weigh %.1f kg, the rider carries %.1f kg", p.origin.weightKg, p.maxWeightKg))
+	}
+
+	for o := 0; o < p.orderCount; o++ {
+		pickup := p.stops[2*o]
+		if p.capacity > 0 && pickup.Size > p.capacity {
+			reasons = append(reasons, fmt.Sprintf("order %d takes %d bag units, the rider carries %d", pickup.OrderID, pickup.Size, p.capacity))
+		}
+		if p.maxWeightKg > 0 && pickup.WeightKg > p.maxWeightKg+costEpsilon {
+			reasons = append(reasons, fmt.Sprintf("order %d weighs %.1f kg, the rider carries %.1f kg", pickup.OrderID, pickup.WeightKg, p.maxWeightKg))
+		}
+
+		// Quickest way to the customer is riding straight there from the restaurant
+		var direct, earliest float64
+		if p.origin.visited&(1<<uint(2*o)) != 0 {
+			direct = p.travelTime(p.origin, 2*o+2)
+			earliest = direct
+		} else {
+			departAt := math.Max(p.travelTime(p.origin, 2*o+1), pickup.ReadyAt)
+			direct = p.travelTime(routeState{at: 2*o + 1, elapsed: departAt}, 2*o+2)
+			earliest = departAt + direct
+		}
+
+		if direct > pickup.MaxInBag+costEpsilon {
+			reasons = append(reasons, fmt.Sprintf("order %d needs %.1f minutes from pickup to drop, its in-bag limit is %.1f", pickup.OrderID, direct, pickup.MaxInBag))
+		}
+		drop := p.stops[2*o+1]
+		if earliest > drop.WindowClose+costEpsilon {
+			reasons = append(reasons, fmt.Sprintf("order %d can be delivered in %.1f minutes at the earliest, its delivery window closes in %.1f", drop.OrderID, earliest, drop.WindowClose))
+		}
+	}
+
+	if len(reasons) == 0 {
+		if exhaustive {
+			reasons = append(reasons, "no visiting order keeps within the rider's capacity, every order's in-bag limit and delivery window at once")
+		} else {
+			reasons = append(reasons, fmt.Sprintf("the %s heuristic found no route within the rider's capacity, the in-bag limits and delivery windows, an exhaustive strategy may", solver))
+		}
+	}
+
+	return &InfeasibleRouteError{Reasons: reasons}
+}
@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/models"
+)
+
+/*
+* One case per reason explainInfeasible gives, a customer 5 km away is 15
+* minutes at the default speed. An overloaded bag only empties, so it is
+* reported next to the window that makes those routes infeasible.
+ */
+func TestExplainInfeasible(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(orders []models.Order, opts *RouteOptions)
+		kms    []float64
+		solver RouteSolver
+		want   string
+	}{
+		{
+			"picked up load over capacity",
+			func(orders []models.Order, opts *RouteOptions) {
+				orders[0].Status = models.OrderStatusPickedUp
+				orders[1].Status = models.OrderStatusPickedUp
+				orders[1].DeliverBefore = minutesAfter(10)
+				opts.Capacity = 1
+			},
+			[]float64{1, 5}, exactSolver{},
+			"orders already picked up take 2 bag units, the rider carries 1",
+		},
+		{
+			"picked up weight over limit",
+			func(orders []models.Order, opts *RouteOptions) {
+				orders[0].Status = models.OrderStatusPickedUp
+				orders[0].WeightKg = 3
+				orders[1].Status = models.OrderStatusPickedUp
+				orders[1].WeightKg = 3
+				orders[1].DeliverBefore = minutesAfter(10)
+				opts.MaxWeightKg = 5
+			},
+			[]float64{1, 5}, exactSolver{},
+			"orders already picked up weigh 6.0 kg, the rider carries 5.0 kg",
+		},
+		{
+			"order larger than the bag",
+			func(orders []models.Order, opts *RouteOptions) {
+				orders[0].Size = 3
+				opts.Capacity = 2
+			},
+			[]float64{1}, exactSolver{},
+			"order 1 takes 3 bag units, the rider carries 2",
+		},
+		{
+			"order heavier than the limit",
+			func(orders []models.Order, opts *RouteOptions) {
+				orders[0].WeightKg = 12
+				opts.MaxWeightKg = 10
+			},
+			[]float64{1}, exactSolver{},
+			"order 1 weighs 12.0 kg, the rider carries 10.0 kg",
+		},
+		{
+			"in-bag limit shorter than the ride",
+			func(orders []models.Order, opts *RouteOptions) {
+				orders[0].MaxInBagMinutes = 10
+			},
+			[]float64{5}, exactSolver{},
+			"order 1 needs 15.0 minutes from pickup to drop, its in-bag limit is 10.0",
+		},
+		{
+			"window closes before the earliest delivery",
+			func(orders []models.Order, opts *RouteOptions) {
+				orders[0].DeliverBefore = minutesAfter(10)
+			},
+			[]float64{5}, exactSolver{},
+			"order 1 can be delivered in 15.0 minutes at the earliest, its delivery window closes in 10.0",
+		},
+		{
+			"constraints only clash together",
+			func(orders []models.Order, opts *RouteOptions) {
+				orders[0].DeliverBefore = minutesAfter(20)
+				orders[1].DeliverBefore = minutesAfter(20)
+			},
+			[]float64{5, -5}, exactSolver{},
+			"no visiting order keeps within the rider's capacity, every order's in-bag limit and delivery window at once",
+		},
+		{
+			"heuristic missed a route",
+			func(orders []models.Order, opts *RouteOptions) {
+				orders[0].DeliverBefore = minutesAfter(20)
+				orders[1].DeliverBefore = minutesAfter(20)
+			},
+			[]float64{5, -5}, nearestNeighborSolver{},
+			"the nearest_neighbor heuristic found no route",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, orders, locations := lineOrders(tt.kms...)
+			opts := RouteOptions{Now: testNow, Solver: tt.solver}
+			tt.setup(orders, &opts)
+
+			_, err := PlanRoute(start, orders, locations, opts)
+			var infeasible *InfeasibleRouteError
+			if !errors.As(err, &infeasible) || !errors.Is(err, ErrNoFeasibleRoute) {
+				t.Fatalf("PlanRoute error = %v, want an InfeasibleRouteError", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("reasons = %q, want %q", infeasible.Reasons, tt.want)
+			}
+		})
+	}
+}
+
+// A customer who takes delivery from a time on keeps the rider waiting at their door
+func TestDeliveryWindowWaitsForOpen(t *testing.T) {
+	start, orders, locations := lineOrders(5)
+	orders[0].DeliverAfter = minutesAfter(30)
+	orders[0].DeliverBefore = minutesAfter(45)
+
+	plan, err := PlanRoute(start, orders, locations, RouteOptions{Now: testNow, Solver: exactSolver{}})
+	if err != nil {
+		t.Fatalf("PlanRoute error = %v", err)
+	}
+	drop := plan.Response.Route[len(plan.Response.Route)-1]
+	if drop.StopType != StopDrop || math.Abs(drop.ETA-30) > 1e-9 || math.Abs(drop.WaitTime-(30-drop.TravelTime)) > 1e-9 {
+		t.Errorf("drop = %+v, want it done when the window opens at 30 minutes", drop)
+	}
+}
+
+// A soft window only costs lateness, the same route is infeasible with a hard one
+func TestSoftWindowKeepsRouteFeasible(t *testing.T) {
+	start, orders, locations := lineOrders(5)
+	orders[0].DeliverBefore = minutesAfter(10)
+
+	if _, err := PlanRoute(start, orders, locations, RouteOptions{Now: testNow, Solver: exactSolver{}}); !errors.Is(err, ErrNoFeasibleRoute) {
+		t.Errorf("hard window error = %v, want ErrNoFeasibleRoute", err)
+	}
+	plan, err := PlanRoute(start, orders, locations, RouteOptions{Now: testNow, SoftWindows: true, Solver: exactSolver{}})
+	if err != nil {
+		t.Fatalf("soft window error = %v", err)
+	}
+	if len(plan.Response.SLABreaches) != 1 {
+		t.Errorf("sla breaches = %+v, want the missed window", plan.Response.SLABreaches)
+	}
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"math"
+	"time"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/models"
+)
+
+// RouteDeviation - How far a rider is from their plan at a GPS ping
+type RouteDeviation struct {
+	// OffRouteKm is the distance from the planned leg, taken as a straight line
+	OffRouteKm float64 `json:"offRouteKm"`
+	// DelayMinutes is how much later than planned the next stop is reached, 0 when on time
+	DelayMinutes float64 `json:"delayMinutes"`
+}
+
+/*
+* MeasureDeviation - Compares a rider at position at time `at` with the
+* planned leg from `from` to the next stop `to`, due to be done at dueAt.
+* The ride to the next stop is estimated the same way routes are.
+* A rider ahead of plan has no delay, even if the plan waits at the stop.
+ */
+func MeasureDeviation(
+	from, to, position models.Location,
+	at, dueAt time.Time,
+	estimator TravelTimeEstimator,
+	speed *SpeedProfile,
+) RouteDeviation {
+	leg := estimatorOrDefault(estimator).EstimateLeg(position, to)
+	travel := leg.Minutes
+	if speed != nil {
+		travel = speed.TravelMinutes(leg.Km, at)
+	}
+	arriveAt := at.Add(time.Duration(travel * float64(time.Minute)))
+
+	return RouteDeviation{
+		OffRouteKm:   distanceToLegInKm(position, from, to),
+		DelayMinutes: math.Max(0, arriveAt.Sub(dueAt).Minutes()),
+	}
+}
+
+// distanceToLegInKm - Distance from p to the segment a-b, on a flat projection around a
+func distanceToLegInKm(p, a, b models.Location) float64 {
+	const kmPerDegree = 111.32
+	cosLat := math.Cos(a.Latitude * math.Pi / 180)
+	project := func(l models.Location) (float64, float64) {
+		return (l.Longitude - a.Longitude) * kmPerDegree * cosLat, (l.Latitude - a.Latitude) * kmPerDegree
+	}
+
+	px, py := project(p)
+	bx, by := project(b)
+	t := 0.0
+	if lengthSq := bx*bx + by*by; lengthSq > 0 {
+		t = math.Max(0, math.Min(1, (px*bx+py*by)/lengthSq))
+	}
+	return math.Hypot(px-t*bx, py-t*by)
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/models"
+)
+
+func TestDistanceToLegInKm(t *testing.T) {
+	// A leg 4 km north from the restaurant
+	a, b := kmNorth(1, "a", 0), kmNorth(2, "b", 4)
+	east := func(l models.Location, km float64) models.Location {
+		l.Longitude += km / (111.32 * math.Cos(l.Latitude*math.Pi/180))
+		return l
+	}
+
+	tests := []struct {
+		name string
+		p    models.Location
+		want float64
+	}{
+		{"on the leg", kmNorth(0, "p", 2), 0},
+		{"beside the middle", east(kmNorth(0, "p", 2), 1), 1},
+		{"behind the start", kmNorth(0, "p", -1.5), 1.5},
+		{"past the end", east(kmNorth(0, "p", 7), 4), 5},
+	}
+	for _, tt := range tests {
+		if got := distanceToLegInKm(tt.p, a, b); math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("%s: distance = %.4f km, want %.4f", tt.name, got, tt.want)
+		}
+	}
+
+	// A leg that goes nowhere is measured to its one point
+	if got := distanceToLegInKm(east(a, 2), a, a); math.Abs(got-2) > 0.01 {
+		t.Errorf("distance to a point leg = %.4f km, want 2", got)
+	}
+}
+
+func TestMeasureDeviation(t *testing.T) {
+	from, to := kmNorth(1, "restaurant", 0), kmNorth(2, "customer", 5)
+	position := kmNorth(0, "rider", 1)
+	estimator := HaversineEstimator{SpeedKmph: 20}
+	travel := estimator.EstimateLeg(position, to).Minutes
+
+	// Due before the rider can get there
+	deviation := MeasureDeviation(from, to, position, testNow, testNow.Add(5*time.Minute), estimator, nil)
+	if math.Abs(deviation.DelayMinutes-(travel-5)) > 1e-6 {
+		t.Errorf("delay = %.4f minutes, want %.4f", deviation.DelayMinutes, travel-5)
+	}
+	if deviation.OffRouteKm > 1e-9 {
+		t.Errorf("rider on the leg is %.4f km off route", deviation.OffRouteKm)
+	}
+
+	// Ahead of plan is no delay
+	deviation = MeasureDeviation(from, to, position, testNow, testNow.Add(time.Hour), estimator, nil)
+	if deviation.DelayMinutes != 0 {
+		t.Errorf("delay ahead of plan = %.4f minutes, want 0", deviation.DelayMinutes)
+	}
+
+	// The rider's vehicle rides the leg at its speed for the hour
+	car := testSpeedProfile(t, "car")
+	leg := estimator.EstimateLeg(position, to)
+	deviation = MeasureDeviation(from, to, position, testNow, testNow, estimator, car)
+	if want := car.TravelMinutes(leg.Km, testNow); math.Abs(deviation.DelayMinutes-want) > 1e-6 {
+		t.Errorf("delay with a speed profile = %.4f minutes, want %.4f", deviation.DelayMinutes, want)
+	}
+}
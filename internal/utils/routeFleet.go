@@ -0,0 +1,474 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/models"
+)
+
+// Fleet objectives accepted on /plan, how the riders' route values add up
+const (
+	// FleetObjectiveSum - Sum of every rider's route objective value
+	FleetObjectiveSum = "sum"
+	// FleetObjectiveMax - The worst rider's route objective value, ties broken by the sum of route times
+	FleetObjectiveMax = "max"
+)
+
+// MaxFleetOrders - Every rider's problem holds the stops of all orders in the plan
+const MaxFleetOrders = maxRouteStops / 2
+
+// maxFleetPasses - Rounds of order moves between riders before the plan is returned
+const maxFleetPasses = 20
+
+// ValidateFleetObjective - Returns the fleet objective to use, sum when empty
+func ValidateFleetObjective(objective string) (string, error) {
+	switch objective {
+	case "":
+		return FleetObjectiveSum, nil
+	case FleetObjectiveSum, FleetObjectiveMax:
+		return objective, nil
+	default:
+		return "", fmt.Errorf("unknown fleet objective %q", objective)
+	}
+}
+
+// FleetRider - A rider a fleet plan can give orders to
+type FleetRider struct {
+	// RiderID is 0 for a rider given only by position
+	RiderID int64
+	Start   models.Location
+	// Speed rescales legs to the rider's vehicle, nil keeps the estimate as is
+	Speed *SpeedProfile
+	// Capacity and MaxWeightKg limit the rider's load, 0 for no limit
+	Capacity    int
+	MaxWeightKg float64
+}
+
+// FleetOptions - Inputs of a fleet plan besides the riders and the orders
+type FleetOptions struct {
+	// Estimator defaults to haversine at a constant speed when nil
+	Estimator TravelTimeEstimator
+	Now       time.Time
+	// Objective is what every rider's route minimizes, makespan when empty
+	Objective string
+	// FleetObjective is how the riders' values add up, sum when empty
+	FleetObjective string
+	SoftWindows    bool
+	// ExactMaxOrders - A rider's route is solved exactly up to this many orders, by local search above
+	ExactMaxOrders int
+}
+
+// FleetRoute - The orders one rider takes and the route to do them
+type FleetRoute struct {
+	// RiderIndex is the rider's position in the request
+	RiderIndex int               `json:"rider_index"`
+	RiderID    int64             `json:"rider_id,omitempty"`
+	OrderIDs   []int             `json:"order_ids"`
+	Route      BestRouteResponse `json:"route"`
+}
+
+// FleetPlan - Which rider takes which orders and in what sequence
+type FleetPlan struct {
+	Objective      string  `json:"objective"`
+	FleetObjective string  `json:"fleet_objective"`
+	ObjectiveValue float64 `json:"objective_value"`
+	// TotalTime is the sum of every rider's route time
+	TotalTime float64      `json:"total_time_minutes"`
+	Routes    []FleetRoute `json:"routes"`
+	// UnassignedOrderIDs are orders no rider can take within the constraints
+	UnassignedOrderIDs []int `json:"unassigned_order_ids"`
+}
+
+/*
+* PlanFleet - Pickup and delivery routing over several riders.
+* Orders are first handed out by regret insertion: each round the order that
+* loses the most by not going to its cheapest rider is placed there. Then
+* every rider's route is re-solved on its own and orders are moved between
+* riders while that improves the fleet objective.
+ */
+func PlanFleet(
+	riders []FleetRider,
+	orders []models.Order,
+	locations []models.Location,
+	opts FleetOptions,
+) (*FleetPlan, error) {
+	if len(riders) == 0 {
+		return nil, fmt.Errorf("a fleet plan needs at least one rider")
+	}
+	if len(orders) > MaxFleetOrders {
+		return nil, fmt.Errorf("too many orders to plan: %d", len(orders))
+	}
+	for _, order := range orders {
+		if order.Status == models.OrderStatusPickedUp {
+			return nil, fmt.Errorf("order %d is already picked up", order.OrderID)
+		}
+	}
+
+	s, err := newFleetSearch(riders, orders, locations, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]int, len(orders))
+	for o := range orders {
+		pending[o] = o
+	}
+	unassigned := s.assign(pending)
+	s.improve()
+	if len(unassigned) > 0 {
+		// Moves may have freed room for orders that fit nowhere before
+		unassigned = s.assign(unassigned)
+		s.improve()
+	}
+
+	return s.plan(unassigned), nil
+}
+
+// fleetSearch - One route problem per rider over all orders, and the stops each rider visits
+type fleetSearch struct {
+	riders         []FleetRider
+	orders         []models.Order
+	problems       []*RouteProblem
+	exactMaxOrders int
+	fleetObjective string
+
+	seqs    [][]int
+	states  []routeState
+	solvers []string
+	optimal []bool
+}
+
+func newFleetSearch(
+	riders []FleetRider,
+	orders []models.Order,
+	locations []models.Location,
+	opts FleetOptions,
+) (*fleetSearch, error) {
+	fleetObjective, err := ValidateFleetObjective(opts.FleetObjective)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &fleetSearch{
+		riders:         riders,
+		orders:         orders,
+		problems:       make([]*RouteProblem, len(riders)),
+		exactMaxOrders: opts.ExactMaxOrders,
+		fleetObjective: fleetObjective,
+		seqs:           make([][]int, len(riders)),
+		states:         make([]routeState, len(riders)),
+		solvers:        make([]string, len(riders)),
+		optimal:        make([]bool, len(riders)),
+	}
+
+	estimator := estimatorOrDefault(opts.Estimator)
+	for r, rider := range riders {
+		if r > 0 {
+			s.problems[r] = s.problems[0].forRider(rider, estimator)
+		} else {
+			problem, err := newRouteProblem(rider.Start, orders, locations, RouteOptions{
+				Estimator:   estimator,
+				Speed:       rider.Speed,
+				Now:         opts.Now,
+				Objective:   opts.Objective,
+				Capacity:    rider.Capacity,
+				MaxWeightKg: rider.MaxWeightKg,
+				SoftWindows: opts.SoftWindows,
+			})
+			if err != nil {
+				return nil, err
+			}
+			s.problems[r] = problem
+		}
+		s.seqs[r] = []int{}
+		s.states[r] = s.problems[r].initialState()
+		s.optimal[r] = true
+	}
+	return s, nil
+}
+
+/*
+* forRider - The same problem for another rider. Only legs from the start
+* are estimated again, legs between stops are shared.
+ */
+func (p *RouteProblem) forRider(rider FleetRider, estimator TravelTimeEstimator) *RouteProblem {
+	q := *p
+	q.start = rider.Start
+	q.speed = rider.Speed
+	q.capacity = rider.Capacity
+	q.maxWeightKg = rider.MaxWeightKg
+
+	q.travel = make([][]Leg, len(p.travel))
+	copy(q.travel, p.travel)
+	q.travel[0] = make([]Leg, len(p.travel))
+	for i, stop := range p.stops {
+		q.travel[0][i+1] = estimator.EstimateLeg(rider.Start, stop.Location)
+	}
+	return &q
+}
+
+// value - Fleet objective with rider r at st, as a state so routes' comparison applies
+func (s *fleetSearch) value(r int, st routeState) routeState {
+	var v routeState
+	for k, state := range s.states {
+		if k == r {
+			state = st
+		}
+		if s.fleetObjective == FleetObjectiveMax {
+			v.cost = math.Max(v.cost, state.cost)
+		} else {
+			v.cost += state.cost
+		}
+		v.elapsed += state.elapsed
+	}
+	return v
+}
+
+func (s *fleetSearch) better(a, b routeState) bool {
+	return s.problems[0].better(a, b)
+}
+
+type fleetInsertion struct {
+	rider int
+	seq   []int
+	st    routeState
+	value routeState
+}
+
+// assign - Regret insertion of the pending orders, returns the ones no rider can take
+func (s *fleetSearch) assign(pending []int) []int {
+	pending = append([]int(nil), pending...)
+	for len(pending) > 0 {
+		var pick fleetInsertion
+		var pickRegret routeState
+		pickIdx := -1
+
+		for idx, o := range pending {
+			var best, second fleetInsertion
+			found := 0
+			for r, problem := range s.problems {
+				seq, st, ok := problem.cheapestInsertion(s.seqs[r], 2*o, 2*o+1)
+				if !ok {
+					continue
+				}
+				ins := fleetInsertion{rider: r, seq: seq, st: st, value: s.value(r, st)}
+				switch {
+				case found == 0 || s.better(ins.value, best.value):
+					second, best = best, ins
+				case found == 1 || s.better(ins.value, second.value):
+					second = ins
+				}
+				found++
+			}
+			if found == 0 {
+				continue
+			}
+
+			// An order only one rider can take goes first
+			regret := routeState{cost: math.Inf(1), elapsed: math.Inf(1)}
+			if found > 1 {
+				regret = routeState{cost: second.value.cost - best.value.cost, elapsed: second.value.elapsed - best.value.elapsed}
+			}
+			if pickIdx == -1 || s.better(pickRegret, regret) {
+				pick, pickRegret, pickIdx = best, regret, idx
+			}
+		}
+		if pickIdx == -1 {
+			break
+		}
+
+		s.seqs[pick.rider] = pick.seq
+		s.states[pick.rider] = pick.st
+		pending = append(pending[:pickIdx], pending[pickIdx+1:]...)
+	}
+	return pending
+}
+
+// improve - Re-solves every route, then moves orders between riders while the fleet gets better
+func (s *fleetSearch) improve() {
+	for r := range s.riders {
+		s.resolve(r)
+	}
+	for pass := 0; pass < maxFleetPasses; pass++ {
+		if !s.relocate() {
+			return
+		}
+	}
+}
+
+/*
+* relocate - Moves the first order it finds whose removal from one rider and
+* cheapest insertion into another makes the fleet better. Both routes are
+* then re-solved. Reports false when no such move exists.
+ */
+func (s *fleetSearch) relocate() bool {
+	current := s.value(-1, routeState{})
+	for a := range s.riders {
+		for _, pickup := range s.seqs[a] {
+			if pickup%2 != 0 {
+				continue
+			}
+			o := pickup / 2
+			without := removeOrder(s.seqs[a], o)
+			stA, ok := s.problems[a].evaluatePrefix(without)
+			if !ok {
+				continue
+			}
+
+			for b := range s.riders {
+				if b == a {
+					continue
+				}
+				seqB, stB, ok := s.problems[b].cheapestInsertion(s.seqs[b], 2*o, 2*o+1)
+				if !ok {
+					continue
+				}
+
+				saved := s.states[a]
+				s.states[a] = stA
+				moved := s.value(b, stB)
+				s.states[a] = saved
+				if !s.better(moved, current) {
+					continue
+				}
+
+				s.seqs[a], s.states[a] = without, stA
+				s.seqs[b], s.states[b] = seqB, stB
+				s.resolve(a)
+				s.resolve(b)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func removeOrder(seq []int, o int) []int {
+	out := make([]int, 0, len(seq))
+	for _, i := range seq {
+		if i/2 != o {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// resolve - Solves rider r's route over its orders alone, keeping the current one if it is no worse
+func (s *fleetSearch) resolve(r int) {
+	problem := s.problems[r]
+	orderIdx := make([]int, 0, len(s.seqs[r])/2)
+	for _, i := range s.seqs[r] {
+		if i%2 == 0 {
+			orderIdx = append(orderIdx, i/2)
+		}
+	}
+	if len(orderIdx) == 0 {
+		s.solvers[r], s.optimal[r] = "", true
+		return
+	}
+
+	solver, err := SelectRouteSolver("", len(orderIdx), s.exactMaxOrders)
+	if err != nil {
+		return
+	}
+	s.solvers[r] = solver.Name()
+
+	sub := problem.subProblem(orderIdx)
+	subSeq, optimal := solver.Solve(sub)
+	s.optimal[r] = optimal
+	if subSeq == nil {
+		return
+	}
+
+	seq := make([]int, len(subSeq))
+	for k, i := range subSeq {
+		seq[k] = 2*orderIdx[i/2] + i%2
+	}
+	if st, ok := problem.evaluatePrefix(seq); ok && !problem.better(s.states[r], st) {
+		s.seqs[r], s.states[r] = seq, st
+	}
+}
+
+// subProblem - p restricted to the given orders, sharing its travel times
+func (p *RouteProblem) subProblem(orderIdx []int) *RouteProblem {
+	points := make([]int, 0, 2*len(orderIdx)+1)
+	points = append(points, 0)
+	stops := make([]routeStop, 0, 2*len(orderIdx))
+	for k, o := range orderIdx {
+		for _, i := range []int{2 * o, 2*o + 1} {
+			stop := p.stops[i]
+			stop.OrderIdx = k
+			stops = append(stops, stop)
+			points = append(points, i+1)
+		}
+	}
+
+	travel := make([][]Leg, len(points))
+	for a, from := range points {
+		travel[a] = make([]Leg, len(points))
+		for b, to := range points {
+			travel[a][b] = p.travel[from][to]
+		}
+	}
+
+	origin := routeState{}
+	if p.bagLimits {
+		origin.bagDeadlines = make([]float64, len(orderIdx))
+		for k := range orderIdx {
+			origin.bagDeadlines[k] = math.Inf(1)
+		}
+	}
+
+	return &RouteProblem{
+		start:       p.start,
+		stops:       stops,
+		orderCount:  len(orderIdx),
+		end:         -1,
+		travel:      travel,
+		speed:       p.speed,
+		now:         p.now,
+		objective:   p.objective,
+		capacity:    p.capacity,
+		maxWeightKg: p.maxWeightKg,
+		bagLimits:   p.bagLimits,
+		origin:      origin,
+		pending:     len(stops),
+	}
+}
+
+func (s *fleetSearch) plan(unassigned []int) *FleetPlan {
+	total := s.value(-1, routeState{})
+	plan := &FleetPlan{
+		Objective:          s.problems[0].objective,
+		FleetObjective:     s.fleetObjective,
+		ObjectiveValue:     total.cost,
+		TotalTime:          total.elapsed,
+		Routes:             make([]FleetRoute, 0, len(s.riders)),
+		UnassignedOrderIDs: make([]int, 0, len(unassigned)),
+	}
+
+	for r, rider := range s.riders {
+		orderIDs := make([]int, 0, len(s.seqs[r])/2)
+		for _, i := range s.seqs[r] {
+			if i%2 == 0 {
+				orderIDs = append(orderIDs, s.orders[i/2].OrderID)
+			}
+		}
+		response := s.problems[r].buildResponse(s.seqs[r])
+		response.Solver = s.solvers[r]
+		response.Optimal = s.optimal[r]
+
+		plan.Routes = append(plan.Routes, FleetRoute{
+			RiderIndex: r,
+			RiderID:    rider.RiderID,
+			OrderIDs:   orderIDs,
+			Route:      response,
+		})
+	}
+	for _, o := range unassigned {
+		plan.UnassignedOrderIDs = append(plan.UnassignedOrderIDs, s.orders[o].OrderID)
+	}
+	return plan
+}
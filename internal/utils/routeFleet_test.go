@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/models"
+)
+
+// kmNorth - A point the given km north (or south when negative) of the test restaurant
+func kmNorth(id int, name string, km float64) models.Location {
+	return models.Location{ID: id, Name: name, Latitude: 12.9 + km/111.2, Longitude: 77.6}
+}
+
+// riderOrders - Order ids of each rider's route, sorted
+func riderOrders(plan *FleetPlan) [][]int {
+	orders := make([][]int, len(plan.Routes))
+	for r, route := range plan.Routes {
+		orders[r] = append([]int{}, route.OrderIDs...)
+		sort.Ints(orders[r])
+	}
+	return orders
+}
+
+// A rider north and one south of town each take the order from their own side
+func TestPlanFleetSplitsByDistance(t *testing.T) {
+	_, orders, locations := lineOrders(6, -6)
+	north, south := kmNorth(10, "north restaurant", 5), kmNorth(11, "south restaurant", -5)
+	locations = append(locations, north, south)
+	orders[0].ResLocationID = int64(north.ID)
+	orders[1].ResLocationID = int64(south.ID)
+	riders := []FleetRider{
+		{RiderID: 7, Start: kmNorth(0, "north rider", 5)},
+		{RiderID: 8, Start: kmNorth(0, "south rider", -5)},
+	}
+
+	plan, err := PlanFleet(riders, orders, locations, FleetOptions{Now: testNow, ExactMaxOrders: 6})
+	if err != nil {
+		t.Fatalf("PlanFleet error = %v", err)
+	}
+	got := riderOrders(plan)
+	if len(got) != 2 || len(got[0]) != 1 || got[0][0] != 1 || len(got[1]) != 1 || got[1][0] != 2 {
+		t.Fatalf("riders take orders %v, want [[1] [2]]", got)
+	}
+	if plan.Routes[0].RiderID != 7 || plan.Routes[1].RiderIndex != 1 {
+		t.Errorf("routes = %+v, want them in rider order", plan.Routes)
+	}
+
+	// The sum objective adds up the riders' routes
+	var value, total float64
+	for _, route := range plan.Routes {
+		value += route.Route.ObjectiveValue
+		total += route.Route.TotalTime
+	}
+	if math.Abs(plan.ObjectiveValue-value) > 1e-6 || math.Abs(plan.TotalTime-total) > 1e-6 {
+		t.Errorf("plan value %.6f in %.6f minutes, routes add up to %.6f in %.6f",
+			plan.ObjectiveValue, plan.TotalTime, value, total)
+	}
+}
+
+/*
+* Two customers a half km apart, riders at the restaurant and just south of it.
+* Summed delivery times are lowest with one rider taking both, the worst
+* rider's are lowest when each takes one.
+ */
+func TestPlanFleetObjectives(t *testing.T) {
+	tests := []struct {
+		fleetObjective string
+		// routeSizes is how many orders each rider takes
+		routeSizes []int
+	}{
+		{FleetObjectiveSum, []int{2, 0}},
+		{FleetObjectiveMax, []int{1, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fleetObjective, func(t *testing.T) {
+			start, orders, locations := lineOrders(5, 5.5)
+			riders := []FleetRider{{Start: start}, {Start: kmNorth(0, "rider", -0.3)}}
+			opts := FleetOptions{Now: testNow, Objective: ObjectiveSumDelivery, FleetObjective: tt.fleetObjective, ExactMaxOrders: 6}
+
+			plan, err := PlanFleet(riders, orders, locations, opts)
+			if err != nil {
+				t.Fatalf("PlanFleet error = %v", err)
+			}
+			got := riderOrders(plan)
+			for r, size := range tt.routeSizes {
+				if len(got[r]) != size {
+					t.Fatalf("riders take orders %v, want %v orders each", got, tt.routeSizes)
+				}
+			}
+			if plan.FleetObjective != tt.fleetObjective {
+				t.Errorf("fleet objective = %q, want %q", plan.FleetObjective, tt.fleetObjective)
+			}
+		})
+	}
+}
+
+// An order no rider's bag fits is left out, the others are still planned
+func TestPlanFleetLeavesUnfitOrders(t *testing.T) {
+	start, orders, locations := lineOrders(5, 3)
+	orders[0].Size = 3
+	riders := []FleetRider{{Start: start, Capacity: 2}, {Start: start, Capacity: 2}}
+
+	plan, err := PlanFleet(riders, orders, locations, FleetOptions{Now: testNow, ExactMaxOrders: 6})
+	if err != nil {
+		t.Fatalf("PlanFleet error = %v", err)
+	}
+	if len(plan.UnassignedOrderIDs) != 1 || plan.UnassignedOrderIDs[0] != 1 {
+		t.Errorf("unassigned orders = %v, want [1]", plan.UnassignedOrderIDs)
+	}
+	assigned := 0
+	for _, route := range plan.Routes {
+		assigned += len(route.OrderIDs)
+	}
+	if assigned != 1 {
+		t.Errorf("%d orders assigned, want 1", assigned)
+	}
+}
+
+func TestPlanFleetRejects(t *testing.T) {
+	start, orders, locations := lineOrders(5)
+	if _, err := PlanFleet(nil, orders, locations, FleetOptions{Now: testNow}); err == nil {
+		t.Error("PlanFleet without riders succeeded")
+	}
+
+	orders[0].Status = models.OrderStatusPickedUp
+	if _, err := PlanFleet([]FleetRider{{Start: start}}, orders, locations, FleetOptions{Now: testNow}); err == nil {
+		t.Error("PlanFleet with a picked up order succeeded")
+	}
+
+	if _, err := ValidateFleetObjective("min"); err == nil {
+		t.Error("ValidateFleetObjective accepted an unknown objective")
+	}
+	if got, err := ValidateFleetObjective(""); err != nil || got != FleetObjectiveSum {
+		t.Errorf("ValidateFleetObjective(\"\") = %q, %v, want sum", got, err)
+	}
+}
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"math"
+	"strings"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/models"
+)
+
+// Route output formats accepted on /order/best_route
+const (
+	FormatJSON     = "json"
+	FormatGeoJSON  = "geojson"
+	FormatPolyline = "polyline"
+)
+
+// GeoJSON types, only the parts a route needs
+type GeoJSONFeatureCollection struct {
+	Type       string                 `json:"type"`
+	Features   []GeoJSONFeature       `json:"features"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type GeoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   GeoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// GeoJSONGeometry - Coordinates are [lon, lat] for a Point and a list of those for a LineString
+type GeoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+/*
+* RouteGeoJSON - The route as a FeatureCollection: a Point for the start and
+* every stop, and a straight LineString for every leg between them.
+* Route level numbers go in the collection's properties.
+ */
+func RouteGeoJSON(route BestRouteResponse) GeoJSONFeatureCollection {
+	features := make([]GeoJSONFeature, 0, 2*len(route.Route)+1)
+	features = append(features, pointFeature(route.Start, map[string]interface{}{
+		"kind": "start",
+	}))
+
+	prev := route.Start
+	for i, step := range route.Route {
+		features = append(features, GeoJSONFeature{
+			Type: "Feature",
+			Geometry: GeoJSONGeometry{
+				Type:        "LineString",
+				Coordinates: [][]float64{lonLat(prev), lonLat(step.Location)},
+			},
+			Properties: map[string]interface{}{
+				"kind":                "leg",
+				"sequence":            i + 1,
+				"to_location_id":      step.LocationID,
+				"order_id":            step.OrderID,
+				"travel_time_minutes": step.TravelTime,
+				"wait_time_minutes":   step.WaitTime,
+				"time_taken_minutes":  step.TimeTaken,
+			},
+		})
+		features = append(features, pointFeature(step.Location, map[string]interface{}{
+			"kind":        "stop",
+			"sequence":    i + 1,
+			"stop_type":   step.StopType,
+			"name":        step.Step,
+			"location_id": step.LocationID,
+			"order_id":    step.OrderID,
+			"eta_minutes": step.ETA,
+		}))
+		prev = step.Location
+	}
+
+	return GeoJSONFeatureCollection{
+		Type:     "FeatureCollection",
+		Features: features,
+		Properties: map[string]interface{}{
+			"total_time_minutes": route.TotalTime,
+			"objective":          route.Objective,
+			"objective_value":    route.ObjectiveValue,
+			"solver":             route.Solver,
+			"optimal":            route.Optimal,
+			"sla_breaches":       route.SLABreaches,
+		},
+	}
+}
+
+func pointFeature(loc models.Location, properties map[string]interface{}) GeoJSONFeature {
+	return GeoJSONFeature{
+		Type:       "Feature",
+		Geometry:   GeoJSONGeometry{Type: "Point", Coordinates: lonLat(loc)},
+		Properties: properties,
+	}
+}
+
+func lonLat(loc models.Location) []float64 {
+	return []float64{loc.Longitude, loc.Latitude}
+}
+
+// RoutePolyline - Start and every stop of the route as an encoded polyline
+func RoutePolyline(route BestRouteResponse) string {
+	points := make([]models.Location, 0, len(route.Route)+1)
+	points = append(points, route.Start)
+	for _, step := range route.Route {
+		points = append(points, step.Location)
+	}
+	return EncodePolyline(points)
+}
+
+/*
+* EncodePolyline - Google's encoded polyline algorithm at 5 decimal places.
+* Each point is stored as the delta from the previous one, zig-zag signed and
+* written 5 bits at a time as printable characters.
+ */
+func EncodePolyline(points []models.Location) string {
+	var b strings.Builder
+	var prevLat, prevLon int64
+	for _, p := range points {
+		lat := int64(math.Round(p.Latitude * 1e5))
+		lon := int64(math.Round(p.Longitude * 1e5))
+		encodePolylineValue(&b, lat-prevLat)
+		encodePolylineValue(&b, lon-prevLon)
+		prevLat, prevLon = lat, lon
+	}
+	return b.String()
+}
+
+func encodePolylineValue(b *strings.Builder, v int64) {
+	u := uint64(v) << 1
+	if v < 0 {
+		u = ^u
+	}
+	for u >= 0x20 {
+		b.WriteByte(byte((0x20 | (u & 0x1f)) + 63))
+		u >>= 5
+	}
+	b.WriteByte(byte(u + 63))
+}
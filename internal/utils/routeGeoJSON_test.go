@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/models"
+)
+
+// The worked example of Google's polyline format documentation
+func TestEncodePolyline(t *testing.T) {
+	points := []models.Location{
+		{Latitude: 38.5, Longitude: -120.2},
+		{Latitude: 40.7, Longitude: -120.95},
+		{Latitude: 43.252, Longitude: -126.453},
+	}
+	if got, want := EncodePolyline(points), "_p~iF~ps|U_ulLnnqC_mqNvxq`@"; got != want {
+		t.Errorf("EncodePolyline = %q, want %q", got, want)
+	}
+	if got := EncodePolyline(nil); got != "" {
+		t.Errorf("EncodePolyline(nil) = %q, want empty", got)
+	}
+}
+
+// Coordinates are rounded to 5 decimals, so points closer than that encode as no move
+func TestEncodePolylineRounds(t *testing.T) {
+	points := []models.Location{
+		{Latitude: 12.971234, Longitude: 77.594561},
+		{Latitude: 12.971231, Longitude: 77.594559},
+	}
+	want := EncodePolyline(points[:1]) + "??"
+	if got := EncodePolyline(points); got != want {
+		t.Errorf("EncodePolyline = %q, want %q", got, want)
+	}
+}
+
+func TestRoutePolylineStartsAtStart(t *testing.T) {
+	start, orders, locations := lineOrders(5)
+	plan, err := PlanRoute(start, orders, locations, RouteOptions{Now: testNow, Solver: exactSolver{}})
+	if err != nil {
+		t.Fatalf("PlanRoute error = %v", err)
+	}
+
+	// The pickup is at the start, the drop 5 km north
+	want := EncodePolyline([]models.Location{start, start, locations[1]})
+	if got := RoutePolyline(plan.Response); got != want {
+		t.Errorf("RoutePolyline = %q, want %q", got, want)
+	}
+}
+
+func TestRouteGeoJSON(t *testing.T) {
+	start, orders, locations := lineOrders(5, -3)
+	plan, err := PlanRoute(start, orders, locations, RouteOptions{Now: testNow, Solver: exactSolver{}})
+	if err != nil {
+		t.Fatalf("PlanRoute error = %v", err)
+	}
+	route := plan.Response
+
+	collection := RouteGeoJSON(route)
+	if collection.Type != "FeatureCollection" || collection.Properties["total_time_minutes"] != route.TotalTime {
+		t.Errorf("collection = %s with properties %v", collection.Type, collection.Properties)
+	}
+	// The start, then a leg and a point for every step
+	if got, want := len(collection.Features), 1+2*len(route.Route); got != want {
+		t.Fatalf("%d features, want %d", got, want)
+	}
+
+	first := collection.Features[0]
+	if first.Geometry.Type != "Point" || first.Properties["kind"] != "start" {
+		t.Errorf("first feature = %+v, want the start point", first)
+	}
+	if coords := first.Geometry.Coordinates.([]float64); coords[0] != start.Longitude || coords[1] != start.Latitude {
+		t.Errorf("start coordinates = %v, want longitude first", coords)
+	}
+
+	prev := start
+	for i, step := range route.Route {
+		leg, stop := collection.Features[1+2*i], collection.Features[2+2*i]
+		coords := leg.Geometry.Coordinates.([][]float64)
+		if leg.Geometry.Type != "LineString" || coords[0][1] != prev.Latitude || coords[1][1] != step.Location.Latitude {
+			t.Errorf("leg %d = %+v, want a line from the previous stop", i+1, leg.Geometry)
+		}
+		if stop.Properties["sequence"] != i+1 || stop.Properties["stop_type"] != step.StopType {
+			t.Errorf("stop %d properties = %v", i+1, stop.Properties)
+		}
+		prev = step.Location
+	}
+}
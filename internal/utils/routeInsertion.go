@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/apperrors"
+	"github.com/SHIVAMSINGH0101/go-demo/internal/models"
+)
+
+// ErrInvalidActiveRoute - The planned stops of a route in progress do not add up
+var ErrInvalidActiveRoute = apperrors.New(apperrors.ErrValidation, "invalid active route")
+
+// PlannedStop - A stop of a route the rider is already following
+type PlannedStop struct {
+	OrderID  int64
+	IsPickup bool
+}
+
+/*
+* ActiveRoute - A route the rider is in the middle of.
+* Start is where the rider is now and Stops are the stops left, in the order
+* the rider will take them. The first Committed stops can not be moved.
+* Orders are the orders with stops left, picked up ones have status PICKED_UP.
+ */
+type ActiveRoute struct {
+	Start     models.Location
+	Orders    []models.Order
+	Stops     []PlannedStop
+	Committed int
+}
+
+// ETADelta - How much later (or earlier) a customer gets their order
+type ETADelta struct {
+	OrderID int64   `json:"order_id"`
+	Before  float64 `json:"eta_before_minutes"`
+	After   float64 `json:"eta_after_minutes"`
+	Delta   float64 `json:"delta_minutes"`
+}
+
+// InsertionOption - One way of fitting the candidate order into the route.
+// Positions are indexes into Route.Route.
+type InsertionOption struct {
+	PickupPosition int               `json:"pickup_position"`
+	DropPosition   int               `json:"drop_position"`
+	DeltaTotalTime float64           `json:"delta_total_time_minutes"`
+	CandidateETA   float64           `json:"candidate_eta_minutes"`
+	ETADeltas      []ETADelta        `json:"eta_deltas"`
+	Route          BestRouteResponse `json:"route"`
+}
+
+// InsertionResult - The route as planned and the cheapest ways to add the candidate to it
+type InsertionResult struct {
+	CandidateOrderID int64             `json:"candidate_order_id"`
+	Current          BestRouteResponse `json:"current"`
+	Insertions       []InsertionOption `json:"insertions"`
+}
+
+/*
+* RankInsertions - Tries every pickup/drop position for candidate after the
+* committed stops, keeping the planned stops in their order, and returns the
+* limit cheapest ones by extra total time.
+ */
+func RankInsertions(
+	route ActiveRoute,
+	candidate models.Order,
+	locations []models.Location,
+	opts RouteOptions,
+	limit int,
+) (*InsertionResult, error) {
+	if route.Committed < 0 || route.Committed > len(route.Stops) {
+		return nil, fmt.Errorf("%w: %d committed stops out of %d", ErrInvalidActiveRoute, route.Committed, len(route.Stops))
+	}
+
+	orders := append(append([]models.Order(nil), route.Orders...), candidate)
+	orderIdx := make(map[int64]int, len(orders))
+	for i, order := range orders {
+		if _, ok := orderIdx[int64(order.OrderID)]; ok {
+			return nil, fmt.Errorf("%w: order %d is already on the route", ErrInvalidActiveRoute, order.OrderID)
+		}
+		orderIdx[int64(order.OrderID)] = i
+	}
+
+	problem, err := newRouteProblem(route.Start, orders, locations, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	seq := make([]int, 0, len(route.Stops))
+	for _, stop := range route.Stops {
+		i, ok := orderIdx[stop.OrderID]
+		if !ok || i == len(route.Orders) {
+			return nil, fmt.Errorf("%w: stop for unknown order %d", ErrInvalidActiveRoute, stop.OrderID)
+		}
+		if stop.IsPickup {
+			seq = append(seq, 2*i)
+		} else {
+			seq = append(seq, 2*i+1)
+		}
+	}
+
+	// Everything but the candidate must be covered by the planned stops, in a valid order
+	if _, ok := problem.evaluatePrefix(seq); !ok || len(seq) != problem.pending-2 {
+		return nil, fmt.Errorf("%w: stops must pick up each order once before dropping it", ErrInvalidActiveRoute)
+	}
+
+	current := problem.buildResponse(seq)
+	currentETAs := problem.dropETAs(seq)
+
+	pickup, drop := 2*len(route.Orders), 2*len(route.Orders)+1
+	options := make([]InsertionOption, 0)
+	next := make([]int, len(seq)+2)
+	for i := route.Committed; i <= len(seq); i++ {
+		for j := i; j <= len(seq); j++ {
+			copy(next, seq[:i])
+			next[i] = pickup
+			copy(next[i+1:], seq[i:j])
+			next[j+1] = drop
+			copy(next[j+2:], seq[j:])
+
+			if _, ok := problem.evaluate(next); !ok {
+				continue
+			}
+
+			response := problem.buildResponse(next)
+			response.Solver = StrategyInsertion
+			etas := problem.dropETAs(next)
+
+			deltas := make([]ETADelta, 0, len(route.Orders))
+			for k, order := range route.Orders {
+				deltas = append(deltas, ETADelta{
+					OrderID: int64(order.OrderID),
+					Before:  currentETAs[k],
+					After:   etas[k],
+					Delta:   etas[k] - currentETAs[k],
+				})
+			}
+
+			options = append(options, InsertionOption{
+				PickupPosition: i,
+				DropPosition:   j + 1,
+				DeltaTotalTime: response.TotalTime - current.TotalTime,
+				CandidateETA:   etas[len(route.Orders)],
+				ETADeltas:      deltas,
+				Route:          response,
+			})
+		}
+	}
+
+	if len(options) == 0 {
+		return nil, problem.explainInfeasible(StrategyInsertion, true)
+	}
+
+	sort.SliceStable(options, func(a, b int) bool {
+		return options[a].DeltaTotalTime < options[b].DeltaTotalTime
+	})
+	if limit > 0 && len(options) > limit {
+		options = options[:limit]
+	}
+
+	return &InsertionResult{
+		CandidateOrderID: int64(candidate.OrderID),
+		Current:          current,
+		Insertions:       options,
+	}, nil
+}
+
+// dropETAs - Minutes from now until each order is dropped, indexed by order
+func (p *RouteProblem) dropETAs(seq []int) []float64 {
+	etas := make([]float64, p.orderCount)
+	st := p.initialState()
+	for _, i := range seq {
+		st, _ = p.advance(st, i)
+		if p.stops[i].Type == StopDrop {
+			etas[p.stops[i].OrderIdx] = st.elapsed
+		}
+	}
+	return etas
+}
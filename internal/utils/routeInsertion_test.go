@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/models"
+)
+
+// One order on its way from the restaurant at the start, the candidate goes just past its customer
+func testActiveRoute() (ActiveRoute, []models.Order, []models.Location) {
+	start, orders, locations := lineOrders(5, 5.5)
+	route := ActiveRoute{
+		Start:  start,
+		Orders: orders[:1],
+		Stops:  []PlannedStop{{OrderID: 1, IsPickup: true}, {OrderID: 1}},
+	}
+	return route, orders, locations
+}
+
+func TestRankInsertions(t *testing.T) {
+	route, orders, locations := testActiveRoute()
+
+	result, err := RankInsertions(route, orders[1], locations, RouteOptions{Now: testNow}, 0)
+	if err != nil {
+		t.Fatalf("RankInsertions error = %v", err)
+	}
+	// Pickup before, between or after the planned stops, the drop anywhere after it
+	if len(result.Insertions) != 6 {
+		t.Fatalf("%d insertions, want all 6", len(result.Insertions))
+	}
+
+	for k, option := range result.Insertions {
+		if k > 0 && option.DeltaTotalTime < result.Insertions[k-1].DeltaTotalTime {
+			t.Errorf("insertion %d is cheaper than the one before it", k)
+		}
+		if got := option.Route.TotalTime - result.Current.TotalTime; math.Abs(option.DeltaTotalTime-got) > 1e-9 {
+			t.Errorf("insertion %d: delta %.6f, routes differ by %.6f", k, option.DeltaTotalTime, got)
+		}
+		for _, delta := range option.ETADeltas {
+			if math.Abs(delta.Delta-(delta.After-delta.Before)) > 1e-9 {
+				t.Errorf("insertion %d: eta delta %+v does not add up", k, delta)
+			}
+		}
+	}
+
+	// Picking up together and dropping the candidate last only adds the last half km
+	best := result.Insertions[0]
+	if best.PickupPosition > 1 || best.DropPosition != 3 {
+		t.Errorf("best insertion picks up at %d and drops at %d, want a pickup at the restaurant and the drop last",
+			best.PickupPosition, best.DropPosition)
+	}
+	if best.ETADeltas[0].Delta != 0 {
+		t.Errorf("best insertion delays the planned order by %.2f minutes", best.ETADeltas[0].Delta)
+	}
+
+	limited, err := RankInsertions(route, orders[1], locations, RouteOptions{Now: testNow}, 2)
+	if err != nil {
+		t.Fatalf("RankInsertions with a limit error = %v", err)
+	}
+	if len(limited.Insertions) != 2 {
+		t.Errorf("%d insertions, want the limit of 2", len(limited.Insertions))
+	}
+}
+
+// Committed stops stay first, the candidate can only go after them
+func TestRankInsertionsKeepsCommittedStops(t *testing.T) {
+	route, orders, locations := testActiveRoute()
+	route.Committed = 2
+
+	result, err := RankInsertions(route, orders[1], locations, RouteOptions{Now: testNow}, 0)
+	if err != nil {
+		t.Fatalf("RankInsertions error = %v", err)
+	}
+	if len(result.Insertions) != 1 {
+		t.Fatalf("%d insertions, want 1", len(result.Insertions))
+	}
+	if option := result.Insertions[0]; option.PickupPosition != 2 || option.DropPosition != 3 {
+		t.Errorf("insertion at %d and %d, want both after the committed stops", option.PickupPosition, option.DropPosition)
+	}
+}
+
+func TestRankInsertionsInvalidRoute(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(route *ActiveRoute)
+	}{
+		{"committed past the stops", func(route *ActiveRoute) { route.Committed = 3 }},
+		{"order listed twice", func(route *ActiveRoute) { route.Orders = append(route.Orders, route.Orders[0]) }},
+		{"drop missing", func(route *ActiveRoute) { route.Stops = route.Stops[:1] }},
+		{"drop before pickup", func(route *ActiveRoute) { route.Stops[0], route.Stops[1] = route.Stops[1], route.Stops[0] }},
+		{"stop of an unknown order", func(route *ActiveRoute) { route.Stops = append(route.Stops, PlannedStop{OrderID: 9}) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route, orders, locations := testActiveRoute()
+			tt.setup(&route)
+
+			_, err := RankInsertions(route, orders[1], locations, RouteOptions{Now: testNow}, 0)
+			if !errors.Is(err, ErrInvalidActiveRoute) {
+				t.Errorf("RankInsertions error = %v, want ErrInvalidActiveRoute", err)
+			}
+		})
+	}
+}
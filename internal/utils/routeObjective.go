@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Route objectives accepted on /order/best_route
+const (
+	// ObjectiveMakespan - Minutes until the last stop is done
+	ObjectiveMakespan = "makespan"
+	// ObjectiveSumDelivery - Sum of every customer's minutes until delivery
+	ObjectiveSumDelivery = "sum_delivery_time"
+	// ObjectiveWeightedLateness - Sum of minutes past each promised-by deadline times the order's SLA weight
+	ObjectiveWeightedLateness = "weighted_lateness"
+)
+
+// costEpsilon - Objective values closer than this are treated as equal
+const costEpsilon = 1e-9
+
+// SLABreach - An order the route delivers after its promised-by deadline
+type SLABreach struct {
+	OrderID       int       `json:"order_id"`
+	PromisedBy    time.Time `json:"promised_by"`
+	ETA           time.Time `json:"eta"`
+	LateByMinutes float64   `json:"late_by_minutes"`
+}
+
+// ValidateObjective - Returns the objective to use, makespan when empty
+func ValidateObjective(objective string) (string, error) {
+	switch objective {
+	case "":
+		return ObjectiveMakespan, nil
+	case ObjectiveMakespan, ObjectiveSumDelivery, ObjectiveWeightedLateness:
+		return objective, nil
+	default:
+		return "", fmt.Errorf("unknown route objective %q", objective)
+	}
+}
+
+/*
+* addCost - Objective cost after moving from st to stop i, reached at elapsed minutes.
+* Every objective only grows along a route and never drops when a stop is
+* reached later, which keeps the exact search bounds valid. Minutes past a soft
+* window times the order's SLA weight are added under every objective, so
+* makespan is the route's minutes plus that lateness.
+ */
+func (p *RouteProblem) addCost(st routeState, i int, elapsed float64) float64 {
+	stop := p.stops[i]
+	var windowLateness float64
+	if stop.Type == StopDrop {
+		windowLateness = stop.Weight * math.Max(0, elapsed-stop.SoftClose)
+	}
+
+	switch p.objective {
+	case ObjectiveSumDelivery:
+		if stop.Type == StopDrop {
+			return st.cost + elapsed + windowLateness
+		}
+		return st.cost
+	case ObjectiveWeightedLateness:
+		if stop.Type == StopDrop {
+			return st.cost + stop.Weight*math.Max(0, elapsed-stop.DueAt) + windowLateness
+		}
+		return st.cost
+	default:
+		return st.cost + elapsed - st.elapsed + windowLateness
+	}
+}
+
+// better - Compares by objective cost, then by total time
+func (p *RouteProblem) better(a, b routeState) bool {
+	if a.cost < b.cost-costEpsilon {
+		return true
+	}
+	if a.cost > b.cost+costEpsilon {
+		return false
+	}
+	return a.elapsed < b.elapsed-costEpsilon
+}
+
+// dominates - a is no worse than b on time, cost and in-bag deadlines, so b's completions can't beat a's
+func dominates(a, b routeState) bool {
+	return a.elapsed <= b.elapsed && a.cost <= b.cost && laterBagDeadlines(a, b)
+}
+
+// worstState - Loses every comparison, used as the starting best
+var worstState = routeState{elapsed: math.MaxFloat64, cost: math.MaxFloat64}
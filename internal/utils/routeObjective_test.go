@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestValidateObjective(t *testing.T) {
+	if got, err := ValidateObjective(""); err != nil || got != ObjectiveMakespan {
+		t.Errorf("ValidateObjective(\"\") = %q, %v, want makespan", got, err)
+	}
+	for _, objective := range []string{ObjectiveMakespan, ObjectiveSumDelivery, ObjectiveWeightedLateness} {
+		if got, err := ValidateObjective(objective); err != nil || got != objective {
+			t.Errorf("ValidateObjective(%q) = %q, %v", objective, got, err)
+		}
+	}
+	if _, err := ValidateObjective("fastest"); err == nil {
+		t.Error("ValidateObjective accepted an unknown objective")
+	}
+}
+
+// firstDrop - The order the route delivers first
+func firstDrop(route []RouteStep) int {
+	for _, step := range route {
+		if step.StopType == StopDrop {
+			return step.OrderID
+		}
+	}
+	return 0
+}
+
+/*
+* Three customers 4 km north and one 3 km south. Finishing soonest means
+* going south first, while the three northern customers wait less in total
+* when they come first.
+ */
+func TestObjectivesPickDifferentRoutes(t *testing.T) {
+	tests := []struct {
+		objective string
+		first     int
+	}{
+		{ObjectiveMakespan, 4},
+		{ObjectiveSumDelivery, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.objective, func(t *testing.T) {
+			start, orders, locations := lineOrders(4, 4.01, 4.02, -3)
+			plan, err := PlanRoute(start, orders, locations, RouteOptions{Now: testNow, Objective: tt.objective, Solver: exactSolver{}})
+			if err != nil {
+				t.Fatalf("PlanRoute error = %v", err)
+			}
+			if got := firstDrop(plan.Response.Route); got != tt.first {
+				t.Errorf("first drop is order %d, want %d", got, tt.first)
+			}
+			if plan.Response.Objective != tt.objective {
+				t.Errorf("objective = %q, want %q", plan.Response.Objective, tt.objective)
+			}
+		})
+	}
+}
+
+func TestObjectiveValues(t *testing.T) {
+	start, orders, locations := lineOrders(5, 5)
+	orders[0].PromisedBy = minutesAfter(10)
+	orders[0].SLAWeight = 3
+
+	plans := make(map[string]*RoutePlan)
+	for _, objective := range []string{ObjectiveMakespan, ObjectiveSumDelivery, ObjectiveWeightedLateness} {
+		plan, err := PlanRoute(start, orders, locations, RouteOptions{Now: testNow, Objective: objective, Solver: exactSolver{}})
+		if err != nil {
+			t.Fatalf("%s: PlanRoute error = %v", objective, err)
+		}
+		plans[objective] = plan
+	}
+
+	// Both customers are at the same spot, so both are reached when the route ends
+	minutes := plans[ObjectiveMakespan].Response.TotalTime
+	if got := plans[ObjectiveMakespan].Response.ObjectiveValue; math.Abs(got-minutes) > 1e-6 {
+		t.Errorf("makespan value = %.6f, want the route's %.6f minutes", got, minutes)
+	}
+	if got := plans[ObjectiveSumDelivery].Response.ObjectiveValue; math.Abs(got-2*minutes) > 1e-6 {
+		t.Errorf("sum of deliveries = %.6f, want %.6f", got, 2*minutes)
+	}
+	if got, want := plans[ObjectiveWeightedLateness].Response.ObjectiveValue, 3*(minutes-10); math.Abs(got-want) > 1e-6 {
+		t.Errorf("weighted lateness = %.6f, want %.6f", got, want)
+	}
+
+	breaches := plans[ObjectiveWeightedLateness].Response.SLABreaches
+	if len(breaches) != 1 || breaches[0].OrderID != 1 || math.Abs(breaches[0].LateByMinutes-(minutes-10)) > 1e-6 {
+		t.Errorf("sla breaches = %+v, want order 1 %.2f minutes late", breaches, minutes-10)
+	}
+}
+
+// A missed soft window is priced by its weighted lateness whatever the objective
+func TestSoftWindowsPricedUnderEveryObjective(t *testing.T) {
+	// The customer is about 15 minutes away, about 5 past the window at weight 2
+	tests := []struct {
+		objective string
+		want      func(minutes float64) float64
+	}{
+		{ObjectiveMakespan, func(m float64) float64 { return m + 2*(m-10) }},
+		{ObjectiveSumDelivery, func(m float64) float64 { return m + 2*(m-10) }},
+		{ObjectiveWeightedLateness, func(m float64) float64 { return 2 * (m - 10) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.objective, func(t *testing.T) {
+			start, orders, locations := lineOrders(5)
+			orders[0].DeliverBefore = minutesAfter(10)
+			orders[0].SLAWeight = 2
+			opts := RouteOptions{Now: testNow, Objective: tt.objective, SoftWindows: true, Solver: exactSolver{}}
+
+			plan, err := PlanRoute(start, orders, locations, opts)
+			if err != nil {
+				t.Fatalf("PlanRoute error = %v", err)
+			}
+			minutes := plan.Response.TotalTime
+			if want := tt.want(minutes); math.Abs(plan.Response.ObjectiveValue-want) > 1e-6 {
+				t.Errorf("objective value = %.6f, want %.6f", plan.Response.ObjectiveValue, want)
+			}
+			breaches := plan.Response.SLABreaches
+			if len(breaches) != 1 || math.Abs(breaches[0].LateByMinutes-(minutes-10)) > 1e-6 ||
+				!breaches[0].PromisedBy.Equal(*orders[0].DeliverBefore) {
+				t.Errorf("sla breaches = %+v, want order 1 %.2f minutes past its window", breaches, minutes-10)
+			}
+		})
+	}
+
+	// Both sequences take as long, only the window tells them apart
+	start, orders, locations := lineOrders(5, -5)
+	orders[0].DeliverBefore = minutesAfter(20)
+	opts := RouteOptions{Now: testNow, Objective: ObjectiveMakespan, SoftWindows: true, Solver: exactSolver{}}
+	plan, err := PlanRoute(start, orders, locations, opts)
+	if err != nil {
+		t.Fatalf("PlanRoute error = %v", err)
+	}
+	if len(plan.Response.SLABreaches) != 0 {
+		t.Errorf("makespan route misses a soft window it can keep: %+v", plan.Response.SLABreaches)
+	}
+}
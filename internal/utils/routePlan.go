@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"github.com/SHIVAMSINGH0101/go-demo/internal/apperrors"
+	"github.com/SHIVAMSINGH0101/go-demo/internal/models"
+)
+
+// ErrNoFeasibleRoute - No visiting sequence satisfies the route constraints
+var ErrNoFeasibleRoute = apperrors.New(apperrors.ErrValidation, "no feasible route")
+
+/*
+* RoutePlan - A solved route for a set of orders. Besides the response it
+* keeps what is needed to ask what extra orders would cost on top of it.
+ */
+type RoutePlan struct {
+	Response BestRouteResponse
+
+	start     models.Location
+	orders    []models.Order
+	locations []models.Location
+	opts      RouteOptions
+	seq       []int
+}
+
+// PlanRoute - Solves the route for orders from start with the solver in opts
+func PlanRoute(
+	start models.Location,
+	orders []models.Order,
+	locations []models.Location,
+	opts RouteOptions,
+) (*RoutePlan, error) {
+	problem, err := newRouteProblem(start, orders, locations, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &RoutePlan{
+		start:     start,
+		orders:    orders,
+		locations: locations,
+		opts:      opts,
+	}
+
+	if problem.pending == 0 {
+		plan.seq = []int{}
+		plan.Response = problem.buildResponse(plan.seq)
+		plan.Response.Solver = opts.Solver.Name()
+		plan.Response.Optimal = true
+		return plan, nil
+	}
+
+	seq, optimal := opts.Solver.Solve(problem)
+	if seq == nil {
+		return nil, problem.explainInfeasible(opts.Solver.Name(), optimal)
+	}
+
+	// Heuristics only rank what they evaluated, so their neighbourhood is ranked too
+	if problem.ranking != nil {
+		if st, ok := problem.evaluate(seq); ok {
+			problem.ranking.offer(problem, seq, st)
+		}
+		if !optimal {
+			problem.offerNeighbors(seq)
+		}
+		seq = problem.ranking.entries[0].seq
+	}
+
+	plan.seq = seq
+	plan.Response = problem.buildResponse(seq)
+	plan.Response.Solver = opts.Solver.Name()
+	plan.Response.Optimal = optimal
+	if problem.ranking != nil {
+		plan.Response.Alternatives = problem.alternatives(plan.Response, seq)
+	}
+	return plan, nil
+}
+
+// BagUnits - Bag units the plan's orders take together
+func (p *RoutePlan) BagUnits() int {
+	return TotalBagUnits(p.orders)
+}
+
+/*
+* InsertionCost - Extra minutes the route takes when extra orders join it.
+* The planned stops keep their relative order and every extra order is put at
+* its cheapest restaurant/customer positions, one after the other.
+ */
+func (p *RoutePlan) InsertionCost(extra []models.Order, extraLocations []models.Location) (float64, BestRouteResponse, error) {
+	orders := append(append([]models.Order(nil), p.orders...), extra...)
+	locations := append(append([]models.Location(nil), p.locations...), extraLocations...)
+
+	problem, err := newRouteProblem(p.start, orders, locations, p.opts)
+	if err != nil {
+		return 0, BestRouteResponse{}, err
+	}
+
+	// Stops of the planned orders keep their indexes, extras are appended after
+	// them and push the waypoints back
+	seq := make([]int, len(p.seq))
+	for k, i := range p.seq {
+		if i >= 2*len(p.orders) {
+			i += 2 * len(extra)
+		}
+		seq[k] = i
+	}
+	for k := range extra {
+		o := len(p.orders) + k
+		pickup := 2 * o
+		if problem.origin.visited&(1<<uint(pickup)) != 0 {
+			pickup = -1
+		}
+
+		var ok bool
+		seq, _, ok = problem.cheapestInsertion(seq, pickup, 2*o+1)
+		if !ok {
+			return 0, BestRouteResponse{}, ErrNoFeasibleRoute
+		}
+	}
+
+	response := problem.buildResponse(seq)
+	response.Solver = StrategyInsertion
+	return response.TotalTime - p.Response.TotalTime, response, nil
+}
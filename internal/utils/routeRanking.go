@@ -0,0 +1,149 @@
+package utils
+
+// MaxRouteAlternatives - Most alternatives a best route request may ask for
+const MaxRouteAlternatives = 10
+
+// RouteAlternative - A runner-up route and how it compares with the best one
+type RouteAlternative struct {
+	Rank           int               `json:"rank"`
+	DeltaTotalTime float64           `json:"delta_total_time_minutes"`
+	DeltaObjective float64           `json:"delta_objective_value"`
+	ETADeltas      []ETADelta        `json:"eta_deltas"`
+	Route          BestRouteResponse `json:"route"`
+}
+
+/*
+* routeRanking - The k best distinct full routes offered so far, best first.
+* Solvers offer every full route they evaluate when the problem has one.
+ */
+type routeRanking struct {
+	k       int
+	entries []rankedRoute
+}
+
+type rankedRoute struct {
+	seq []int
+	st  routeState
+}
+
+func newRouteRanking(k int) *routeRanking {
+	return &routeRanking{k: k, entries: make([]rankedRoute, 0, k+1)}
+}
+
+// threshold - A route has to beat this to get into the ranking
+func (r *routeRanking) threshold() routeState {
+	if len(r.entries) < r.k {
+		return worstState
+	}
+	return r.entries[r.k-1].st
+}
+
+func (r *routeRanking) offer(p *RouteProblem, seq []int, st routeState) {
+	if !p.better(st, r.threshold()) {
+		return
+	}
+	for _, e := range r.entries {
+		if sameSequence(e.seq, seq) {
+			return
+		}
+	}
+
+	pos := len(r.entries)
+	for pos > 0 && p.better(st, r.entries[pos-1].st) {
+		pos--
+	}
+	r.entries = append(r.entries, rankedRoute{})
+	copy(r.entries[pos+1:], r.entries[pos:])
+	r.entries[pos] = rankedRoute{seq: append([]int(nil), seq...), st: st}
+	if len(r.entries) > r.k {
+		r.entries = r.entries[:r.k]
+	}
+}
+
+func sameSequence(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// offerNeighbors - Offers every valid route one or-opt or 2-opt move away from seq
+func (p *RouteProblem) offerNeighbors(seq []int) {
+	candidate := make([]int, len(seq))
+	try := func() {
+		if st, ok := p.evaluate(candidate); ok {
+			p.ranking.offer(p, candidate, st)
+		}
+	}
+
+	for i := 0; i < len(seq); i++ {
+		for j := 0; j < len(seq); j++ {
+			if i != j {
+				moveStop(candidate, seq, i, j)
+				try()
+			}
+		}
+	}
+	for i := 0; i < len(seq)-1; i++ {
+		for j := i + 1; j < len(seq); j++ {
+			copy(candidate, seq)
+			for l, r := i, j; l < r; l, r = l+1, r-1 {
+				candidate[l], candidate[r] = candidate[r], candidate[l]
+			}
+			try()
+		}
+	}
+}
+
+// alternatives - Ranked routes after the best one, compared with it
+func (p *RouteProblem) alternatives(best BestRouteResponse, bestSeq []int) []RouteAlternative {
+	bestETAs := p.dropETAs(bestSeq)
+	alternatives := make([]RouteAlternative, 0, len(p.ranking.entries))
+	for _, e := range p.ranking.entries {
+		if sameSequence(e.seq, bestSeq) {
+			continue
+		}
+
+		route := p.buildResponse(e.seq)
+		etas := p.dropETAs(e.seq)
+		deltas := make([]ETADelta, 0, len(etas))
+		for o, eta := range etas {
+			drop := 2*o + 1
+			if !containsStop(e.seq, drop) {
+				continue
+			}
+			deltas = append(deltas, ETADelta{
+				OrderID: int64(p.stops[drop].OrderID),
+				Before:  bestETAs[o],
+				After:   eta,
+				Delta:   eta - bestETAs[o],
+			})
+		}
+
+		alternatives = append(alternatives, RouteAlternative{
+			Rank:           len(alternatives) + 1,
+			DeltaTotalTime: route.TotalTime - best.TotalTime,
+			DeltaObjective: route.ObjectiveValue - best.ObjectiveValue,
+			ETADeltas:      deltas,
+			Route:          route,
+		})
+		if len(alternatives) == p.ranking.k-1 {
+			break
+		}
+	}
+	return alternatives
+}
+
+func containsStop(seq []int, stop int) bool {
+	for _, s := range seq {
+		if s == stop {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// routeKey - The stops of a route in order, tells routes apart
+func routeKey(route BestRouteResponse) string {
+	key := ""
+	for _, step := range route.Route {
+		key += fmt.Sprintf("%s:%d ", step.StopType, step.OrderID)
+	}
+	return key
+}
+
+func TestAlternativesRankedAfterBest(t *testing.T) {
+	start, orders, locations := lineOrders(2, 4, -3)
+	opts := RouteOptions{Now: testNow, Objective: ObjectiveSumDelivery, Alternatives: 4, Solver: exactSolver{}}
+
+	plan, err := PlanRoute(start, orders, locations, opts)
+	if err != nil {
+		t.Fatalf("PlanRoute error = %v", err)
+	}
+	best := plan.Response
+	if len(best.Alternatives) != 4 {
+		t.Fatalf("%d alternatives, want 4", len(best.Alternatives))
+	}
+
+	seen := map[string]bool{routeKey(best): true}
+	for k, alt := range best.Alternatives {
+		if alt.Rank != k+1 {
+			t.Errorf("alternative %d has rank %d", k, alt.Rank)
+		}
+		if alt.DeltaObjective < -1e-9 || (k > 0 && alt.DeltaObjective < best.Alternatives[k-1].DeltaObjective-1e-9) {
+			t.Errorf("alternative %d costs %.6f more, out of order", alt.Rank, alt.DeltaObjective)
+		}
+		if got := alt.Route.ObjectiveValue - best.ObjectiveValue; math.Abs(alt.DeltaObjective-got) > 1e-9 {
+			t.Errorf("alternative %d: delta %.6f, routes differ by %.6f", alt.Rank, alt.DeltaObjective, got)
+		}
+		if got := alt.Route.TotalTime - best.TotalTime; math.Abs(alt.DeltaTotalTime-got) > 1e-9 {
+			t.Errorf("alternative %d: time delta %.6f, routes differ by %.6f", alt.Rank, alt.DeltaTotalTime, got)
+		}
+		if len(alt.ETADeltas) != len(orders) {
+			t.Errorf("alternative %d has %d eta deltas, want one per order", alt.Rank, len(alt.ETADeltas))
+		}
+
+		key := routeKey(alt.Route)
+		if seen[key] {
+			t.Errorf("alternative %d repeats route %s", alt.Rank, key)
+		}
+		seen[key] = true
+	}
+}
+
+// The exact search must rank the same runner-ups as trying every sequence
+func TestExactAlternativesMatchBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(13))
+	for instance := 0; instance < 25; instance++ {
+		start, orders, locations, opts := randomInstance(rng, 2+rng.Intn(2), variantPlain)
+		opts.Objective = ObjectiveSumDelivery
+		opts.Alternatives = 3
+
+		brute, _ := planWith(t, bruteForceSolver{}, start, orders, locations, opts)
+		exact, _ := planWith(t, exactSolver{}, start, orders, locations, opts)
+		if len(exact.Response.Alternatives) != len(brute.Response.Alternatives) {
+			t.Fatalf("instance %d: %d alternatives, brute force %d", instance,
+				len(exact.Response.Alternatives), len(brute.Response.Alternatives))
+		}
+		for k, alt := range exact.Response.Alternatives {
+			if want := brute.Response.Alternatives[k].DeltaObjective; math.Abs(alt.DeltaObjective-want) > 1e-6 {
+				t.Errorf("instance %d: alternative %d costs %.6f more, brute force %.6f", instance, k+1, alt.DeltaObjective, want)
+			}
+		}
+	}
+}
+
+// A single order has one route, there is nothing to rank after it
+func TestAlternativesOfSingleRoute(t *testing.T) {
+	start, orders, locations := lineOrders(5)
+	plan, err := PlanRoute(start, orders, locations, RouteOptions{Now: testNow, Alternatives: 3, Solver: exactSolver{}})
+	if err != nil {
+		t.Fatalf("PlanRoute error = %v", err)
+	}
+	if len(plan.Response.Alternatives) != 0 {
+		t.Errorf("alternatives = %+v, want none", plan.Response.Alternatives)
+	}
+}
@@ -0,0 +1,388 @@
+package utils
+
+import (
+	"fmt"
+)
+
+// Route solver strategies accepted on /order/best_route
+const (
+	StrategyBruteForce      = "brute_force"
+	StrategyExact           = "exact"
+	StrategyNearestNeighbor = "nearest_neighbor"
+	StrategyInsertion       = "insertion"
+	StrategyLocalSearch     = "local_search"
+)
+
+// RouteSolver - Finds a visiting sequence for all stops of a RouteProblem.
+// optimal is true only when the solver proves no cheaper sequence exists.
+type RouteSolver interface {
+	Name() string
+	Solve(p *RouteProblem) (seq []int, optimal bool)
+}
+
+var routeSolvers = map[string]RouteSolver{
+	StrategyBruteForce:      bruteForceSolver{},
+	StrategyExact:           exactSolver{},
+	StrategyNearestNeighbor: nearestNeighborSolver{},
+	StrategyInsertion:       insertionSolver{},
+	StrategyLocalSearch:     localSearchSolver{},
+}
+
+/*
+* SelectRouteSolver - Returns the solver for the requested strategy. When no
+* strategy is given, exact search is used up to exactMaxOrders orders and local
+* search above that. Exhaustive strategies are refused above exactMaxOrders.
+ */
+func SelectRouteSolver(strategy string, orderCount, exactMaxOrders int) (RouteSolver, error) {
+	if strategy == "" {
+		if orderCount <= exactMaxOrders {
+			return routeSolvers[StrategyExact], nil
+		}
+		return routeSolvers[StrategyLocalSearch], nil
+	}
+
+	solver, ok := routeSolvers[strategy]
+	if !ok {
+		return nil, fmt.Errorf("unknown route strategy %q", strategy)
+	}
+
+	if (strategy == StrategyBruteForce || strategy == StrategyExact) && orderCount > exactMaxOrders {
+		return nil, fmt.Errorf("strategy %q supports at most %d orders", strategy, exactMaxOrders)
+	}
+
+	return solver, nil
+}
+
+// bruteForceSolver - Tries every sequence where each restaurant comes before its customer
+type bruteForceSolver struct{}
+
+func (bruteForceSolver) Name() string { return StrategyBruteForce }
+
+func (bruteForceSolver) Solve(p *RouteProblem) ([]int, bool) {
+	best := worstState
+	var bestSeq []int
+	seq := make([]int, 0, len(p.stops))
+
+	var visit func(st routeState)
+	visit = func(st routeState) {
+		if len(seq) == p.pending {
+			st := p.finish(st)
+			if p.ranking != nil {
+				p.ranking.offer(p, seq, st)
+			}
+			if p.better(st, best) {
+				best = st
+				bestSeq = append([]int(nil), seq...)
+			}
+			return
+		}
+		for i := range p.stops {
+			next, ok := p.step(st, i)
+			if !ok {
+				continue
+			}
+			seq = append(seq, i)
+			visit(next)
+			seq = seq[:len(seq)-1]
+		}
+	}
+	visit(p.initialState())
+
+	return bestSeq, true
+}
+
+// exactSolver - Branch and bound, see exactSearch
+type exactSolver struct{}
+
+func (exactSolver) Name() string { return StrategyExact }
+
+func (exactSolver) Solve(p *RouteProblem) ([]int, bool) {
+	return newExactSearch(p).run(), true
+}
+
+/*
+* exactSearch - Branch and bound over all sequences where every restaurant
+* comes before its customer. A branch is cut when it is already no better than
+* the best full route, or when the same set of stops was already reached at
+* the same last stop sooner and at no higher cost (the rest of the route can't do better).
+* When alternatives are ranked, branches are cut against the last ranked route
+* instead and the second cut is off, as it would hide runner-up routes.
+ */
+type exactSearch struct {
+	problem *RouteProblem
+	best    routeState
+	bestSeq []int
+	seq     []int
+	seen    map[seenKey]routeState
+}
+
+type seenKey struct {
+	visited uint64
+	last    int
+}
+
+func newExactSearch(problem *RouteProblem) *exactSearch {
+	return &exactSearch{
+		problem: problem,
+		best:    worstState,
+		seq:     make([]int, 0, len(problem.stops)),
+		seen:    make(map[seenKey]routeState),
+	}
+}
+
+func (s *exactSearch) run() []int {
+	s.visit(s.problem.initialState(), -1)
+	return s.bestSeq
+}
+
+func (s *exactSearch) visit(st routeState, last int) {
+	ranking := s.problem.ranking
+	if ranking == nil && !s.problem.better(st, s.best) {
+		return
+	}
+	if ranking != nil && !s.problem.better(st, ranking.threshold()) {
+		return
+	}
+
+	if len(s.seq) == s.problem.pending {
+		st := s.problem.finish(st)
+		if ranking != nil {
+			ranking.offer(s.problem, s.seq, st)
+		}
+		if s.problem.better(st, s.best) {
+			s.best = st
+			s.bestSeq = append([]int(nil), s.seq...)
+		}
+		return
+	}
+
+	if ranking == nil {
+		key := seenKey{visited: st.visited, last: last}
+		if seen, ok := s.seen[key]; ok && dominates(seen, st) {
+			return
+		}
+		s.seen[key] = st
+	}
+
+	for i := range s.problem.stops {
+		next, ok := s.problem.step(st, i)
+		if !ok {
+			continue
+		}
+		s.seq = append(s.seq, i)
+		s.visit(next, i)
+		s.seq = s.seq[:len(s.seq)-1]
+	}
+}
+
+// nearestNeighborSolver - Always moves to the feasible stop that is reached soonest
+type nearestNeighborSolver struct{}
+
+func (nearestNeighborSolver) Name() string { return StrategyNearestNeighbor }
+
+func (nearestNeighborSolver) Solve(p *RouteProblem) ([]int, bool) {
+	st := p.initialState()
+	seq := make([]int, 0, len(p.stops))
+
+	for len(seq) < p.pending {
+		bestStop := -1
+		var bestState routeState
+		for i := range p.stops {
+			next, ok := p.step(st, i)
+			if !ok {
+				continue
+			}
+			if bestStop == -1 || next.elapsed < bestState.elapsed {
+				bestStop = i
+				bestState = next
+			}
+		}
+		if bestStop == -1 {
+			return nil, false
+		}
+		seq = append(seq, bestStop)
+		st = bestState
+	}
+
+	return seq, false
+}
+
+/*
+* insertionSolver - Builds the route one order or waypoint at a time. Each
+* round, every unrouted order is tried at every pickup/drop position pair of
+* the current route, every waypoint at every position, and the cheapest
+* feasible insertion is committed.
+ */
+type insertionSolver struct{}
+
+func (insertionSolver) Name() string { return StrategyInsertion }
+
+func (insertionSolver) Solve(p *RouteProblem) ([]int, bool) {
+	// Units 0..orderCount-1 are orders, the rest are waypoints
+	unitCount := p.orderCount
+	for _, stop := range p.stops {
+		if stop.Type == StopWaypoint {
+			unitCount++
+		}
+	}
+	routed := make([]bool, unitCount)
+	seq := make([]int, 0, len(p.stops))
+
+	for round := 0; round < unitCount; round++ {
+		best := worstState
+		var bestSeq []int
+		bestUnit := -1
+
+		for u := 0; u < unitCount; u++ {
+			if routed[u] {
+				continue
+			}
+			pickup, drop := -1, p.orderCount+u
+			if u < p.orderCount {
+				pickup, drop = 2*u, 2*u+1
+				if p.origin.visited&(1<<uint(pickup)) != 0 {
+					pickup = -1 // already picked up, only the drop is routed
+				}
+			}
+			candidate, st, ok := p.cheapestInsertion(seq, pickup, drop)
+			if ok && p.better(st, best) {
+				best = st
+				bestSeq = candidate
+				bestUnit = u
+			}
+		}
+		if bestUnit == -1 {
+			return nil, false
+		}
+		routed[bestUnit] = true
+		seq = bestSeq
+	}
+
+	return seq, false
+}
+
+// cheapestInsertion - Best way to place pickup then drop into seq, scored on the
+// partial route and the ride to the end. A negative pickup inserts the drop
+// alone, as for an order already picked up or a waypoint.
+func (p *RouteProblem) cheapestInsertion(seq []int, pickup, drop int) ([]int, routeState, bool) {
+	best := worstState
+	var bestSeq []int
+
+	try := func(candidate []int) {
+		st, ok := p.evaluatePrefix(candidate)
+		st = p.finish(st)
+		if ok && p.better(st, best) {
+			best = st
+			bestSeq = append([]int(nil), candidate...)
+		}
+	}
+
+	if pickup < 0 {
+		candidate := make([]int, len(seq)+1)
+		for j := 0; j <= len(seq); j++ {
+			copy(candidate, seq[:j])
+			candidate[j] = drop
+			copy(candidate[j+1:], seq[j:])
+			try(candidate)
+		}
+		return bestSeq, best, bestSeq != nil
+	}
+
+	candidate := make([]int, len(seq)+2)
+	for i := 0; i <= len(seq); i++ {
+		for j := i; j <= len(seq); j++ {
+			copy(candidate, seq[:i])
+			candidate[i] = pickup
+			copy(candidate[i+1:], seq[i:j])
+			candidate[j+1] = drop
+			copy(candidate[j+2:], seq[j:])
+			try(candidate)
+		}
+	}
+
+	return bestSeq, best, bestSeq != nil
+}
+
+/*
+* localSearchSolver - Starts from the insertion route and keeps applying
+* or-opt (move one stop elsewhere) and 2-opt (reverse a segment) moves while
+* they improve the objective. Moves that put a customer before its restaurant
+* are rejected.
+ */
+type localSearchSolver struct{}
+
+func (localSearchSolver) Name() string { return StrategyLocalSearch }
+
+func (localSearchSolver) Solve(p *RouteProblem) ([]int, bool) {
+	seq, _ := insertionSolver{}.Solve(p)
+	if seq == nil {
+		return nil, false
+	}
+
+	best, _ := p.evaluate(seq)
+	candidate := make([]int, len(seq))
+
+	for improved := true; improved; {
+		improved = false
+
+		// or-opt: take the stop at i out and put it back at j
+		for i := 0; i < len(seq); i++ {
+			for j := 0; j < len(seq); j++ {
+				if i == j {
+					continue
+				}
+				moveStop(candidate, seq, i, j)
+				st, ok := p.evaluate(candidate)
+				if ok && p.ranking != nil {
+					p.ranking.offer(p, candidate, st)
+				}
+				if ok && p.better(st, best) {
+					best = st
+					copy(seq, candidate)
+					improved = true
+				}
+			}
+		}
+
+		// 2-opt: reverse seq[i..j]
+		for i := 0; i < len(seq)-1; i++ {
+			for j := i + 1; j < len(seq); j++ {
+				copy(candidate, seq)
+				for l, r := i, j; l < r; l, r = l+1, r-1 {
+					candidate[l], candidate[r] = candidate[r], candidate[l]
+				}
+				st, ok := p.evaluate(candidate)
+				if ok && p.ranking != nil {
+					p.ranking.offer(p, candidate, st)
+				}
+				if ok && p.better(st, best) {
+					best = st
+					copy(seq, candidate)
+					improved = true
+				}
+			}
+		}
+	}
+
+	return seq, false
+}
+
+// moveStop - Writes src into dst with the element at from moved to index to
+func moveStop(dst, src []int, from, to int) {
+	moved := src[from]
+	k := 0
+	for idx, v := range src {
+		if idx == from {
+			continue
+		}
+		if k == to {
+			dst[k] = moved
+			k++
+		}
+		dst[k] = v
+		k++
+	}
+	if k == to {
+		dst[k] = moved
+	}
+}
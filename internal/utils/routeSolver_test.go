@@ -0,0 +1,246 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/models"
+)
+
+var testNow = time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+
+// Instance variants the solvers are compared on
+const (
+	variantPlain    = "plain"
+	variantCapacity = "capacity"
+	variantWindows  = "windows"
+	variantEnd      = "end"
+	variantInBag    = "in_bag"
+	variantSoft     = "soft_windows"
+)
+
+func randomLocation(rng *rand.Rand, id int, name string) models.Location {
+	return models.Location{
+		ID:        id,
+		Name:      name,
+		Latitude:  12.90 + rng.Float64()*0.1,
+		Longitude: 77.55 + rng.Float64()*0.1,
+	}
+}
+
+func minutesAfter(minutes float64) *time.Time {
+	t := testNow.Add(time.Duration(minutes * float64(time.Minute)))
+	return &t
+}
+
+// randomInstance - n orders around one city, with the constraints of the variant
+func randomInstance(rng *rand.Rand, n int, variant string) (models.Location, []models.Order, []models.Location, RouteOptions) {
+	start := randomLocation(rng, 0, "start")
+	orders := make([]models.Order, 0, n)
+	locations := make([]models.Location, 0, 2*n)
+	opts := RouteOptions{Now: testNow}
+
+	for i := 0; i < n; i++ {
+		res := randomLocation(rng, 2*i+1, fmt.Sprintf("restaurant %d", i))
+		cus := randomLocation(rng, 2*i+2, fmt.Sprintf("customer %d", i))
+		locations = append(locations, res, cus)
+
+		order := models.Order{
+			OrderID:           i + 1,
+			ResLocationID:     int64(res.ID),
+			CusLocationID:     int64(cus.ID),
+			PrepTimeInMinutes: rng.Float64() * 20,
+			Status:            models.OrderStatusReady,
+			PromisedBy:        minutesAfter(20 + rng.Float64()*40),
+			SLAWeight:         1 + float64(rng.Intn(3)),
+			CreatedAt:         testNow.Add(-time.Duration(rng.Intn(10)) * time.Minute),
+		}
+
+		switch variant {
+		case variantCapacity:
+			order.Size = 1 + rng.Intn(2)
+			order.WeightKg = 1 + rng.Float64()*4
+			if i == 0 && rng.Intn(2) == 0 {
+				order.Status = models.OrderStatusPickedUp
+			}
+		case variantWindows, variantSoft:
+			if rng.Intn(3) > 0 {
+				open := rng.Float64() * 40
+				order.DeliverAfter = minutesAfter(open)
+				order.DeliverBefore = minutesAfter(open + 15 + rng.Float64()*40)
+			}
+		case variantInBag:
+			order.MaxInBagMinutes = 20 + rng.Float64()*30
+		}
+		orders = append(orders, order)
+	}
+
+	switch variant {
+	case variantSoft:
+		opts.SoftWindows = true
+	case variantCapacity:
+		opts.Capacity = 2 + rng.Intn(2)
+		opts.MaxWeightKg = 6 + rng.Float64()*6
+	case variantEnd:
+		end := randomLocation(rng, 100, "hub")
+		opts.End = &end
+		if n <= 3 {
+			opts.Waypoints = []models.Location{randomLocation(rng, 101, "via")}
+		}
+	}
+
+	return start, orders, locations, opts
+}
+
+// planWith - Plans the instance with one solver, copying opts so solvers don't share a ranking
+func planWith(t *testing.T, solver RouteSolver, start models.Location, orders []models.Order, locations []models.Location, opts RouteOptions) (*RoutePlan, error) {
+	t.Helper()
+	opts.Solver = solver
+	plan, err := PlanRoute(start, orders, locations, opts)
+	if err != nil && !errors.Is(err, ErrNoFeasibleRoute) {
+		t.Fatalf("%s: unexpected error %v", solver.Name(), err)
+	}
+	return plan, err
+}
+
+// The exact search must find a route exactly as good as trying every sequence
+func TestExactSolverMatchesBruteForce(t *testing.T) {
+	variants := []string{variantPlain, variantCapacity, variantWindows, variantSoft, variantEnd, variantInBag}
+	objectives := []string{ObjectiveMakespan, ObjectiveSumDelivery, ObjectiveWeightedLateness}
+
+	for _, variant := range variants {
+		for _, objective := range objectives {
+			t.Run(variant+"/"+objective, func(t *testing.T) {
+				rng := rand.New(rand.NewSource(int64(len(variant)*31 + len(objective))))
+				for instance := 0; instance < 25; instance++ {
+					start, orders, locations, opts := randomInstance(rng, 1+rng.Intn(4), variant)
+					opts.Objective = objective
+
+					brute, bruteErr := planWith(t, bruteForceSolver{}, start, orders, locations, opts)
+					exact, exactErr := planWith(t, exactSolver{}, start, orders, locations, opts)
+
+					if (bruteErr == nil) != (exactErr == nil) {
+						t.Fatalf("instance %d: brute force error %v, exact error %v", instance, bruteErr, exactErr)
+					}
+					if bruteErr != nil {
+						continue
+					}
+					if !exact.Response.Optimal {
+						t.Errorf("instance %d: exact route is not marked optimal", instance)
+					}
+					if math.Abs(exact.Response.ObjectiveValue-brute.Response.ObjectiveValue) > 1e-6 ||
+						math.Abs(exact.Response.TotalTime-brute.Response.TotalTime) > 1e-6 {
+						t.Errorf("instance %d: exact cost %.6f in %.6f minutes, brute force %.6f in %.6f",
+							instance, exact.Response.ObjectiveValue, exact.Response.TotalTime,
+							brute.Response.ObjectiveValue, brute.Response.TotalTime)
+					}
+				}
+			})
+		}
+	}
+}
+
+// Heuristics may miss the optimum but never return a route that breaks a constraint
+func TestHeuristicsReturnFeasibleRoutes(t *testing.T) {
+	heuristics := []RouteSolver{nearestNeighborSolver{}, insertionSolver{}, localSearchSolver{}}
+	variants := []string{variantPlain, variantCapacity, variantWindows, variantSoft, variantEnd, variantInBag}
+
+	for _, variant := range variants {
+		t.Run(variant, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(int64(len(variant))))
+			for instance := 0; instance < 25; instance++ {
+				start, orders, locations, opts := randomInstance(rng, 1+rng.Intn(4), variant)
+				opts.Objective = ObjectiveSumDelivery
+
+				exact, err := planWith(t, exactSolver{}, start, orders, locations, opts)
+				for _, solver := range heuristics {
+					plan, heuristicErr := planWith(t, solver, start, orders, locations, opts)
+					if heuristicErr != nil {
+						continue
+					}
+					if err != nil {
+						t.Fatalf("instance %d: %s found a route where exact search found none", instance, solver.Name())
+					}
+
+					problem, _ := newRouteProblem(start, orders, locations, opts)
+					if _, ok := problem.evaluate(plan.seq); !ok {
+						t.Errorf("instance %d: %s returned an infeasible sequence %v", instance, solver.Name(), plan.seq)
+					}
+					if plan.Response.ObjectiveValue < exact.Response.ObjectiveValue-1e-6 {
+						t.Errorf("instance %d: %s cost %.6f beats the optimum %.6f",
+							instance, solver.Name(), plan.Response.ObjectiveValue, exact.Response.ObjectiveValue)
+					}
+				}
+			}
+		})
+	}
+}
+
+// Batches too large for the exact search still get a full route from every heuristic
+func TestHeuristicsPlanLargeBatches(t *testing.T) {
+	heuristics := []RouteSolver{nearestNeighborSolver{}, insertionSolver{}, localSearchSolver{}}
+	rng := rand.New(rand.NewSource(2))
+	start, orders, locations, opts := randomInstance(rng, 20, variantPlain)
+	opts.Objective = ObjectiveSumDelivery
+
+	for _, solver := range heuristics {
+		plan, err := planWith(t, solver, start, orders, locations, opts)
+		if err != nil {
+			t.Fatalf("%s: no route for %d orders: %v", solver.Name(), len(orders), err)
+		}
+		if got := len(plan.Response.Route); got != 2*len(orders) {
+			t.Errorf("%s: route has %d steps, want %d", solver.Name(), got, 2*len(orders))
+		}
+		if plan.Response.Solver != solver.Name() || plan.Response.Optimal {
+			t.Errorf("%s: route says solver %q, optimal %v", solver.Name(), plan.Response.Solver, plan.Response.Optimal)
+		}
+	}
+}
+
+func TestSelectRouteSolver(t *testing.T) {
+	tests := []struct {
+		strategy string
+		orders   int
+		want     string
+		wantErr  bool
+	}{
+		{"", 6, StrategyExact, false},
+		{"", 7, StrategyLocalSearch, false},
+		{StrategyInsertion, 20, StrategyInsertion, false},
+		{StrategyBruteForce, 7, "", true},
+		{"simulated_annealing", 2, "", true},
+	}
+	for _, tt := range tests {
+		solver, err := SelectRouteSolver(tt.strategy, tt.orders, 6)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("SelectRouteSolver(%q, %d) error = %v", tt.strategy, tt.orders, err)
+			continue
+		}
+		if err == nil && solver.Name() != tt.want {
+			t.Errorf("SelectRouteSolver(%q, %d) = %s, want %s", tt.strategy, tt.orders, solver.Name(), tt.want)
+		}
+	}
+}
+
+// lineOrders - Orders from a restaurant at the start to customers the given km north (or south when negative)
+func lineOrders(kms ...float64) (models.Location, []models.Order, []models.Location) {
+	start := models.Location{ID: 1, Name: "restaurant", Latitude: 12.9, Longitude: 77.6}
+	locations := []models.Location{start}
+	orders := make([]models.Order, 0, len(kms))
+	for i, km := range kms {
+		cus := models.Location{ID: i + 2, Name: fmt.Sprintf("customer %d", i), Latitude: 12.9 + km/111.2, Longitude: 77.6}
+		locations = append(locations, cus)
+		orders = append(orders, models.Order{
+			OrderID:       i + 1,
+			ResLocationID: 1,
+			CusLocationID: int64(cus.ID),
+			Status:        models.OrderStatusReady,
+			CreatedAt:     testNow,
+		})
+	}
+	return start, orders, locations
+}
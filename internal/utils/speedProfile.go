@@ -0,0 +1,185 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+const hoursPerWeek = 7 * 24
+
+/*
+* SpeedProfiles - Rider speed by vehicle type and hour of the week in the
+* city's timezone. A profile rides the km of an estimated leg at the
+* vehicle's speed while the leg is being ridden.
+ */
+type SpeedProfiles struct {
+	DefaultVehicle string
+	vehicles       map[string]*SpeedProfile
+}
+
+// SpeedProfile - km/h for each local hour of the week, index is weekday*24 + hour (Sunday = 0)
+type SpeedProfile struct {
+	Vehicle  string
+	speeds   [hoursPerWeek]float64
+	location *time.Location
+}
+
+// speedProfilesFile - JSON layout of the file pointed to by ROUTE_SPEED_PROFILES
+type speedProfilesFile struct {
+	DefaultVehicle string                        `json:"default_vehicle"`
+	Vehicles       map[string]vehicleSpeedConfig `json:"vehicles"`
+}
+
+type vehicleSpeedConfig struct {
+	SpeedKmph float64       `json:"speed_kmph"`
+	Buckets   []speedBucket `json:"buckets"`
+}
+
+// speedBucket - Overrides speed on the given weekdays (Sunday = 0) for hours [start_hour, end_hour)
+type speedBucket struct {
+	Days      []int   `json:"days"`
+	StartHour int     `json:"start_hour"`
+	EndHour   int     `json:"end_hour"`
+	SpeedKmph float64 `json:"speed_kmph"`
+}
+
+var weekdays = []int{1, 2, 3, 4, 5}
+
+// defaultSpeedProfiles - Used when no profile file is configured
+var defaultSpeedProfiles = speedProfilesFile{
+	DefaultVehicle: "motorbike",
+	Vehicles: map[string]vehicleSpeedConfig{
+		"bicycle": {SpeedKmph: 14},
+		"motorbike": {
+			SpeedKmph: defaultSpeedKmph,
+			Buckets: []speedBucket{
+				{Days: weekdays, StartHour: 8, EndHour: 11, SpeedKmph: 14},
+				{Days: weekdays, StartHour: 17, EndHour: 21, SpeedKmph: 14},
+			},
+		},
+		"car": {
+			SpeedKmph: 22,
+			Buckets: []speedBucket{
+				{Days: weekdays, StartHour: 8, EndHour: 11, SpeedKmph: 12},
+				{Days: weekdays, StartHour: 17, EndHour: 21, SpeedKmph: 12},
+			},
+		},
+	},
+}
+
+/*
+* LoadSpeedProfiles - Reads profiles from a JSON file, or returns the built-in
+* ones when path is empty. Their hours are those of the IANA timezone, e.g.
+* Asia/Kolkata, whatever zone the times they are asked about are in.
+ */
+func LoadSpeedProfiles(path, timezone string) (*SpeedProfiles, error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid speed profile timezone %q: %w", timezone, err)
+	}
+
+	if path == "" {
+		return newSpeedProfiles(defaultSpeedProfiles, location)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read speed profiles: %w", err)
+	}
+
+	var file speedProfilesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse speed profiles: %w", err)
+	}
+
+	return newSpeedProfiles(file, location)
+}
+
+func newSpeedProfiles(file speedProfilesFile, location *time.Location) (*SpeedProfiles, error) {
+	profiles := &SpeedProfiles{
+		DefaultVehicle: file.DefaultVehicle,
+		vehicles:       make(map[string]*SpeedProfile),
+	}
+
+	for vehicle, cfg := range file.Vehicles {
+		if cfg.SpeedKmph <= 0 {
+			return nil, fmt.Errorf("vehicle %q: speed_kmph must be positive", vehicle)
+		}
+
+		profile := &SpeedProfile{Vehicle: vehicle, location: location}
+		for h := range profile.speeds {
+			profile.speeds[h] = cfg.SpeedKmph
+		}
+
+		for _, bucket := range cfg.Buckets {
+			if bucket.SpeedKmph <= 0 || bucket.StartHour < 0 || bucket.EndHour > 24 || bucket.StartHour >= bucket.EndHour {
+				return nil, fmt.Errorf("vehicle %q: invalid bucket %+v", vehicle, bucket)
+			}
+			for _, day := range bucket.Days {
+				if day < 0 || day > 6 {
+					return nil, fmt.Errorf("vehicle %q: invalid day %d", vehicle, day)
+				}
+				for h := bucket.StartHour; h < bucket.EndHour; h++ {
+					profile.speeds[day*24+h] = bucket.SpeedKmph
+				}
+			}
+		}
+
+		profiles.vehicles[vehicle] = profile
+	}
+
+	if _, ok := profiles.vehicles[profiles.DefaultVehicle]; !ok {
+		return nil, fmt.Errorf("default vehicle %q has no speed profile", profiles.DefaultVehicle)
+	}
+
+	return profiles, nil
+}
+
+// ForVehicle - Profile for a vehicle type, an empty type means the default vehicle
+func (s *SpeedProfiles) ForVehicle(vehicle string) (*SpeedProfile, error) {
+	if vehicle == "" {
+		vehicle = s.DefaultVehicle
+	}
+
+	profile, ok := s.vehicles[vehicle]
+	if !ok {
+		return nil, fmt.Errorf("unknown vehicle type %q", vehicle)
+	}
+	return profile, nil
+}
+
+func (p *SpeedProfile) speedAt(t time.Time) float64 {
+	return p.speeds[int(t.Weekday())*24+t.Hour()]
+}
+
+/*
+* TravelMinutes - Minutes to ride distanceKm on this profile when departing
+* at departAt. Speed is applied hour by hour, so a leg that runs into rush
+* hour only slows down for the part inside it and leaving later never gets
+* the rider there earlier.
+ */
+func (p *SpeedProfile) TravelMinutes(distanceKm float64, departAt time.Time) float64 {
+	remainingKm := distanceKm
+	total := 0.0
+	t := departAt.In(p.location)
+
+	for remainingKm > 1e-12 {
+		speed := p.speedAt(t)
+		// Local wall clock hour, Truncate would break for half hour offsets like IST
+		nextHour := time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		untilNextHour := nextHour.Sub(t).Minutes()
+		coverableKm := speed * untilNextHour / 60
+		if coverableKm >= remainingKm {
+			total += remainingKm / speed * 60
+			break
+		}
+
+		remainingKm -= coverableKm
+		total += untilNextHour
+		t = nextHour
+	}
+
+	return total
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/models"
+)
+
+func testSpeedProfile(t *testing.T, vehicle string) *SpeedProfile {
+	t.Helper()
+	profiles, err := LoadSpeedProfiles("", "Asia/Kolkata")
+	if err != nil {
+		t.Fatalf("LoadSpeedProfiles: %v", err)
+	}
+	profile, err := profiles.ForVehicle(vehicle)
+	if err != nil {
+		t.Fatalf("ForVehicle: %v", err)
+	}
+	return profile
+}
+
+// Rush hour is 08:00-11:00 in the city, the same instant in UTC must land in it
+func TestTravelMinutesUsesCityTimezone(t *testing.T) {
+	car := testSpeedProfile(t, "car")
+
+	// Monday 03:00 UTC is 08:30 IST
+	departAt := time.Date(2026, 1, 5, 3, 0, 0, 0, time.UTC)
+	if got, want := car.TravelMinutes(6, departAt), 30.0; math.Abs(got-want) > 1e-9 {
+		t.Errorf("6 km in rush hour = %.2f minutes, want %.2f at 12 km/h", got, want)
+	}
+
+	// Monday 08:30 UTC is 14:00 IST
+	departAt = time.Date(2026, 1, 5, 8, 30, 0, 0, time.UTC)
+	if got, want := car.TravelMinutes(11, departAt), 30.0; math.Abs(got-want) > 1e-9 {
+		t.Errorf("11 km off peak = %.2f minutes, want %.2f at 22 km/h", got, want)
+	}
+}
+
+func TestTravelMinutesAcrossHours(t *testing.T) {
+	motorbike := testSpeedProfile(t, "motorbike")
+	ist, _ := time.LoadLocation("Asia/Kolkata")
+
+	// 10 minutes at 20 km/h up to 08:00, then 14 km/h for the remaining 2 km
+	departAt := time.Date(2026, 1, 5, 7, 50, 0, 0, ist)
+	want := 10 + 2.0/14*60
+	if got := motorbike.TravelMinutes(20.0/6+2, departAt); math.Abs(got-want) > 1e-9 {
+		t.Errorf("TravelMinutes = %.4f, want %.4f", got, want)
+	}
+}
+
+func TestLoadSpeedProfilesRejectsUnknownTimezone(t *testing.T) {
+	if _, err := LoadSpeedProfiles("", "Mars/Olympus_Mons"); err == nil {
+		t.Error("LoadSpeedProfiles accepted an unknown timezone")
+	}
+}
+
+// A road graph leg reports the km of its path, not its minutes at the default speed
+func TestRoadGraphLegDistance(t *testing.T) {
+	dir := t.TempDir()
+	nodes := filepath.Join(dir, "nodes.csv")
+	edges := filepath.Join(dir, "edges.csv")
+	if err := os.WriteFile(nodes, []byte("id,latitude,longitude\na,12.90,77.60\nb,12.95,77.60\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(edges, []byte("from_id,to_id,distance_km,speed_kmph\na,b,6,60\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	graph, err := LoadRoadGraph(nodes, edges)
+	if err != nil {
+		t.Fatalf("LoadRoadGraph: %v", err)
+	}
+	leg := graph.EstimateLeg(
+		models.Location{Latitude: 12.90, Longitude: 77.60},
+		models.Location{Latitude: 12.95, Longitude: 77.60},
+	)
+	if math.Abs(leg.Minutes-6) > 1e-9 || math.Abs(leg.Km-6) > 1e-9 {
+		t.Errorf("leg = %+v, want 6 minutes over 6 km", leg)
+	}
+
+	// Off peak a bicycle rides the 6 road km at 14 km/h
+	bicycle := testSpeedProfile(t, "bicycle")
+	if got, want := bicycle.TravelMinutes(leg.Km, testNow), 6.0/14*60; math.Abs(got-want) > 1e-9 {
+		t.Errorf("bicycle minutes = %.4f, want %.4f", got, want)
+	}
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/config"
+	"github.com/SHIVAMSINGH0101/go-demo/internal/models"
+)
+
+// Travel time estimators selectable through config.RoutingConfig
+const (
+	EstimatorHaversine = "haversine"
+	EstimatorRoadGraph = "road_graph"
+)
+
+// defaultSpeedKmph - Rider speed used by the haversine estimator
+const defaultSpeedKmph = 20.0
+
+// TravelTimeEstimator - Approx ride a rider takes to go from -> to
+type TravelTimeEstimator interface {
+	EstimateLeg(from, to models.Location) Leg
+}
+
+// Leg - Estimated minutes and km of a ride, speed profiles ride Km at the vehicle's own speed
+type Leg struct {
+	Minutes float64
+	Km      float64
+}
+
+// NewTravelTimeEstimator - Builds the estimator selected in config
+func NewTravelTimeEstimator(cfg config.RoutingConfig) (TravelTimeEstimator, error) {
+	switch cfg.TravelEstimator {
+	case "", EstimatorHaversine:
+		return HaversineEstimator{SpeedKmph: defaultSpeedKmph}, nil
+	case EstimatorRoadGraph:
+		return LoadRoadGraph(cfg.RoadGraphNodesPath, cfg.RoadGraphEdgesPath)
+	default:
+		return nil, fmt.Errorf("unknown travel time estimator %q", cfg.TravelEstimator)
+	}
+}
+
+// HaversineEstimator - Straight line distance covered at a constant speed
+type HaversineEstimator struct {
+	SpeedKmph float64
+}
+
+// Returns approax time taken to reach from -> to location
+func (e HaversineEstimator) TravelTimeInMinutes(from, to models.Location) float64 {
+	return e.EstimateLeg(from, to).Minutes
+}
+
+func (e HaversineEstimator) EstimateLeg(from, to models.Location) Leg {
+	dist := haversine(from.Latitude, from.Longitude, to.Latitude, to.Longitude)
+	return Leg{Minutes: (dist / e.SpeedKmph) * 60, Km: dist}
+}
+
+// DistanceInKm - Straight line distance between two locations
+func DistanceInKm(from, to models.Location) float64 {
+	return haversine(from.Latitude, from.Longitude, to.Latitude, to.Longitude)
+}
+
+// Users haversine formula to get approax distance between from -> to lat and lon
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusInKM = 6371
+	dLat := (lat2 - lat1) * (3.14159 / 180)
+	dLon := (lon2 - lon1) * (3.14159 / 180)
+	lat1 *= (3.14159 / 180)
+	lat2 *= (3.14159 / 180)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Sin(dLon/2)*math.Sin(dLon/2)*math.Cos(lat1)*math.Cos(lat2)
+
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+
+	return earthRadiusInKM * c
+}
@@ -1,131 +1,502 @@
 package utils
 
 import (
+	"fmt"
 	"math"
+	"math/bits"
+	"time"
 
 	"github.com/SHIVAMSINGH0101/go-demo/internal/models"
 )
 
-// RouteStep - Each step taken in the optimal approach
+// RouteStep - Each step taken in the optimal approach.
+// TimeTaken is TravelTime plus WaitTime for food that isn't ready yet
+// or for the customer's delivery window to open.
+// ETA is the minutes from the start until the step is done.
+// OrderID is 0 on waypoint and end steps.
 type RouteStep struct {
 	Step       string  `json:"step"`
+	StopType   string  `json:"stop_type"`
 	LocationID int     `json:"location_id"`
+	OrderID    int     `json:"order_id"`
+	TravelTime float64 `json:"travel_time_minutes"`
+	WaitTime   float64 `json:"wait_time_minutes"`
 	TimeTaken  float64 `json:"time_taken_minutes"`
+	ETA        float64 `json:"eta_minutes"`
+
+	// Location is kept for map output, see RouteGeoJSON
+	Location models.Location `json:"-"`
 }
 
+// Stop types of a route step
+const (
+	StopPickup = "pickup"
+	StopDrop   = "drop"
+	// StopWaypoint - A location the route has to pass through, in any order
+	StopWaypoint = "waypoint"
+	// StopEnd - Where the route finishes, always last
+	StopEnd = "end"
+)
+
 // BestRouteResponse - Optimal steps for delivery partner to take
 type BestRouteResponse struct {
-	TotalTime float64     `json:"total_time_minutes"`
-	Route     []RouteStep `json:"route"`
+	TotalTime      float64     `json:"total_time_minutes"`
+	Objective      string      `json:"objective"`
+	ObjectiveValue float64     `json:"objective_value"`
+	Solver         string      `json:"solver"`
+	Optimal        bool        `json:"optimal"`
+	Route          []RouteStep `json:"route"`
+	SLABreaches    []SLABreach `json:"sla_breaches"`
+	// Alternatives are only filled when asked for
+	Alternatives []RouteAlternative `json:"alternatives,omitempty"`
+	// Polyline is the encoded path of the route, only filled when asked for
+	Polyline string `json:"polyline,omitempty"`
+
+	// Start is where the rider sets off, kept for map output
+	Start models.Location `json:"-"`
+}
+
+// RouteOptions - Inputs of a best route computation besides the orders
+type RouteOptions struct {
+	Solver RouteSolver
+	// Estimator defaults to haversine at a constant speed when nil
+	Estimator TravelTimeEstimator
+	// Speed rescales estimated legs to the rider's vehicle and time of day, nil keeps the estimate as is
+	Speed *SpeedProfile
+	// Now is when the rider starts from their current location
+	Now time.Time
+	// Objective is what the solvers minimize, makespan when empty
+	Objective string
+	// Capacity is how many bag units the rider carries at once, 0 for no limit
+	Capacity int
+	// MaxWeightKg is the heaviest load the rider carries at once, 0 for no limit
+	MaxWeightKg float64
+	// SoftWindows lets a route miss an order's deliver_before at a lateness
+	// penalty instead of making the route infeasible, under every objective
+	SoftWindows bool
+	// Alternatives is how many runner-up routes to return besides the best
+	Alternatives int
+	// End is where the route has to finish, nil for an open path ending at the last stop
+	End *models.Location
+	// Waypoints are locations the route has to pass through, in any order
+	Waypoints []models.Location
+}
+
+// routeStop - A location the rider has to visit for one of the orders.
+// Stops are laid out as pickup(order i) at 2*i and drop(order i) at 2*i+1,
+// followed by the waypoints and the end location, if any.
+// ReadyAt is minutes after the route starts when the food is ready, it can be negative.
+// DueAt is the same for the promised-by deadline, +Inf when the order has none.
+// MaxInBag is how long the order may be carried, +Inf when it has no limit.
+// WindowOpen and WindowClose bound when a customer takes delivery, they are
+// -Inf and +Inf without a window. A soft window has no WindowClose, it is in
+// SoftClose instead, +Inf when there is none.
+type routeStop struct {
+	Location models.Location
+	Type     string
+	OrderIdx int
+	OrderID  int
+	ReadyAt  float64
+	DueAt    float64
+	Weight   float64
+	// PromisedBy - Wall clock deadline behind DueAt, zero when there is none
+	PromisedBy  time.Time
+	Size        int
+	WeightKg    float64
+	MaxInBag    float64
+	WindowOpen  float64
+	WindowClose float64
+	SoftClose   float64
+	// DeliverBefore - Wall clock time behind SoftClose, zero when there is none
+	DeliverBefore time.Time
+}
+
+// RouteProblem - Everything a solver needs to cost a visiting sequence.
+// Point 0 of the travel matrix is the start, point i+1 is stops[i], its legs
+// are as estimated and a speed profile rides them when the route is costed.
+// Pickups of orders already picked up are marked visited in origin and
+// pending counts the stops a full route still has to visit, the end aside.
+// end is the index of the end stop, -1 for an open path.
+type RouteProblem struct {
+	start       models.Location
+	stops       []routeStop
+	orderCount  int
+	end         int
+	travel      [][]Leg
+	speed       *SpeedProfile
+	now         time.Time
+	objective   string
+	capacity    int
+	maxWeightKg float64
+	// bagLimits is set when some order has an in-bag limit
+	bagLimits bool
+	origin    routeState
+	pending   int
+	// ranking collects the best routes when alternatives are asked for, nil otherwise
+	ranking *routeRanking
+}
+
+// routeState - Where the rider is after visiting a prefix of the stops,
+// the objective cost run up so far and what is in the bag.
+// bagDeadlines holds, per order, the elapsed minutes by which it must be
+// dropped. It is nil without in-bag limits and copied before every change.
+type routeState struct {
+	at           int
+	elapsed      float64
+	cost         float64
+	visited      uint64
+	load         int
+	weightKg     float64
+	bagDeadlines []float64
 }
 
+// maxRouteStops - Visited stops are tracked in a uint64 bitmask
+const maxRouteStops = 64
+
 func GetBestRoute(
 	userLocation models.Location,
-	orders []models.Order, 
+	orders []models.Order,
 	locations []models.Location,
-) BestRouteResponse {
-	// We want to find the best route starting from userLocation, visiting both restaurants and customers in some order.
+	opts RouteOptions,
+) (BestRouteResponse, error) {
+	// We want to find the best route starting from userLocation, visiting all restaurants and customers in some order.
 	// The route must visit each restaurant before its customer.
+	// At a restaurant the rider only waits for whatever prep time is left when they arrive.
+	plan, err := PlanRoute(userLocation, orders, locations, opts)
+	if err != nil {
+		return BestRouteResponse{}, err
+	}
+	return plan.Response, nil
+}
 
-	// Possible sequences of indices representing the order of visits:
-	// We have two orders: O1 and O2.
-	// Constraints:
-	// - O1.Restaurant before O1.Customer
-	// - O2.Restaurant before O2.Customer
-	// So the valid permutations of [R1, R2, C1, C2] with constraints are:
-	// R1 R2 C1 C2
-	// R1 R2 C2 C1
-	// R2 R1 C1 C2 
-	// R2 R1 C2 C1
-	// R1 C1 R2 C2
-	// R2 C2 R1 C1
-
-	bestTime := math.MaxFloat64
-	// Stores the best route sequence from all sequences
-	bestRoute := BestRouteResponse{}
-	// Get all possible location's sequences that we can try
-	allPosibleSequences := getAllLocationSequences(orders)
+func newRouteProblem(
+	userLocation models.Location,
+	orders []models.Order,
+	locations []models.Location,
+	opts RouteOptions,
+) (*RouteProblem, error) {
+	stopCount := 2*len(orders) + len(opts.Waypoints)
+	if opts.End != nil {
+		stopCount++
+	}
+	if stopCount > maxRouteStops {
+		return nil, fmt.Errorf("too many stops to route: %d", stopCount)
+	}
+
+	objective, err := ValidateObjective(opts.Objective)
+	if err != nil {
+		return nil, err
+	}
 
 	locationMap := make(map[int]models.Location)
 	for _, location := range locations {
 		locationMap[location.ID] = location
 	}
 
-	// Loop all possible route sequences
-	for _, currSeq := range allPosibleSequences {
-		currLocation := userLocation
-		totalTime := 0.0
-		routeTaken := make([]RouteStep, 0)
-
-		for _, nextLocationId := range currSeq {
-			nextloc := locationMap[int(nextLocationId)]
-			timeTaken := getTravelTimeInMinutes(currLocation, nextloc)
+	stops := make([]routeStop, 0, stopCount)
+	var origin routeState
+	bagLimits := false
+	for i, order := range orders {
+		resLoc, ok := locationMap[int(order.ResLocationID)]
+		if !ok {
+			return nil, fmt.Errorf("restaurant location %d not found for order %d", order.ResLocationID, order.OrderID)
+		}
+		cusLoc, ok := locationMap[int(order.CusLocationID)]
+		if !ok {
+			return nil, fmt.Errorf("customer location %d not found for order %d", order.CusLocationID, order.OrderID)
+		}
 
-			if nextLocationId == orders[0].ResLocationID {
-				timeTaken += orders[0].PrepTimeInMinutes
-			}
-			if nextLocationId == orders[1].ResLocationID {
-				timeTaken += orders[1].PrepTimeInMinutes
+		readyAt := order.CreatedAt.Add(time.Duration(order.PrepTimeInMinutes * float64(time.Minute)))
+		dueAt := math.Inf(1)
+		var promisedBy time.Time
+		if order.PromisedBy != nil {
+			promisedBy = *order.PromisedBy
+			dueAt = promisedBy.Sub(opts.Now).Minutes()
+		}
+		windowOpen, windowClose, softClose := math.Inf(-1), math.Inf(1), math.Inf(1)
+		var deliverBefore time.Time
+		if order.DeliverAfter != nil {
+			windowOpen = order.DeliverAfter.Sub(opts.Now).Minutes()
+		}
+		if order.DeliverBefore != nil {
+			before := order.DeliverBefore.Sub(opts.Now).Minutes()
+			if opts.SoftWindows {
+				softClose = before
+				deliverBefore = *order.DeliverBefore
+			} else {
+				windowClose = before
 			}
+		}
+		weight := order.SLAWeight
+		if weight <= 0 {
+			weight = 1
+		}
+		size := BagUnits(order)
+		maxInBag := math.Inf(1)
+		if order.MaxInBagMinutes > 0 {
+			maxInBag = order.MaxInBagMinutes
+			bagLimits = true
+		}
+		stops = append(stops,
+			routeStop{Location: resLoc, Type: StopPickup, OrderIdx: i, OrderID: order.OrderID, ReadyAt: readyAt.Sub(opts.Now).Minutes(),
+				Size: size, WeightKg: order.WeightKg, MaxInBag: maxInBag, WindowOpen: math.Inf(-1), WindowClose: math.Inf(1), SoftClose: math.Inf(1)},
+			routeStop{Location: cusLoc, Type: StopDrop, OrderIdx: i, OrderID: order.OrderID, DueAt: dueAt, Weight: weight, PromisedBy: promisedBy,
+				Size: size, WeightKg: order.WeightKg, MaxInBag: maxInBag, WindowOpen: windowOpen, WindowClose: windowClose,
+				SoftClose: softClose, DeliverBefore: deliverBefore},
+		)
+		if order.Status == models.OrderStatusPickedUp {
+			origin.visited |= 1 << uint(2*i)
+			origin.load += size
+			origin.weightKg += order.WeightKg
+		}
+	}
 
-			totalTime += timeTaken
-			currLocation = nextloc
-			
-			routeTaken = append(routeTaken, RouteStep{
-				Step: nextloc.Name,
-				LocationID: nextloc.ID,
-				TimeTaken: timeTaken,
-			})
+	// Time already spent in the bag isn't known, in-bag limits of picked up orders count from now
+	if bagLimits {
+		origin.bagDeadlines = make([]float64, len(orders))
+		for i := range orders {
+			origin.bagDeadlines[i] = math.Inf(1)
+			if origin.visited&(1<<uint(2*i)) != 0 {
+				origin.bagDeadlines[i] = stops[2*i].MaxInBag
+			}
 		}
-		
-		// Update best route sequence
-		if totalTime < bestTime {
-			bestTime = totalTime
-			bestRoute = BestRouteResponse{
-				TotalTime: bestTime,
-				Route: routeTaken,
-			} 
+	}
+
+	for _, waypoint := range opts.Waypoints {
+		stops = append(stops, pathStop(waypoint, StopWaypoint))
+	}
+	end := -1
+	if opts.End != nil {
+		end = len(stops)
+		stops = append(stops, pathStop(*opts.End, StopEnd))
+	}
+	pending := len(stops) - bits.OnesCount64(origin.visited)
+	if end >= 0 {
+		pending--
+	}
+
+	points := make([]models.Location, 0, len(stops)+1)
+	points = append(points, userLocation)
+	for _, stop := range stops {
+		points = append(points, stop.Location)
+	}
+
+	estimator := estimatorOrDefault(opts.Estimator)
+
+	var ranking *routeRanking
+	if opts.Alternatives > 0 {
+		ranking = newRouteRanking(opts.Alternatives + 1)
+	}
+
+	return &RouteProblem{
+		start:       userLocation,
+		stops:       stops,
+		orderCount:  len(orders),
+		end:         end,
+		travel:      travelMatrix(points, estimator),
+		speed:       opts.Speed,
+		now:         opts.Now,
+		objective:   objective,
+		capacity:    opts.Capacity,
+		maxWeightKg: opts.MaxWeightKg,
+		bagLimits:   bagLimits,
+		origin:      origin,
+		pending:     pending,
+		ranking:     ranking,
+	}, nil
+}
+
+// estimatorOrDefault - Haversine at a constant speed when no estimator is configured
+func estimatorOrDefault(estimator TravelTimeEstimator) TravelTimeEstimator {
+	if estimator == nil {
+		return HaversineEstimator{SpeedKmph: defaultSpeedKmph}
+	}
+	return estimator
+}
+
+// pathStop - A waypoint or end stop, it carries no order and has no time limits
+func pathStop(location models.Location, stopType string) routeStop {
+	return routeStop{
+		Location:    location,
+		Type:        stopType,
+		OrderIdx:    -1,
+		DueAt:       math.Inf(1),
+		MaxInBag:    math.Inf(1),
+		WindowOpen:  math.Inf(-1),
+		WindowClose: math.Inf(1),
+		SoftClose:   math.Inf(1),
+	}
+}
+
+// travelMatrix - Estimates every point to point leg once so solvers can look them up
+func travelMatrix(points []models.Location, estimator TravelTimeEstimator) [][]Leg {
+	matrix := make([][]Leg, len(points))
+	for i, from := range points {
+		matrix[i] = make([]Leg, len(points))
+		for j, to := range points {
+			if i != j {
+				matrix[i][j] = estimator.EstimateLeg(from, to)
+			}
 		}
 	}
+	return matrix
+}
+
+func (p *RouteProblem) initialState() routeState {
+	return p.origin
+}
+
+// canVisit - A customer can only be visited once its restaurant is visited
+// and a restaurant only while the order fits in the bag. Waypoints can be
+// visited any time, the end is only reached through finish.
+func (p *RouteProblem) canVisit(st routeState, i int) bool {
+	if st.visited&(1<<uint(i)) != 0 {
+		return false
+	}
+	switch p.stops[i].Type {
+	case StopPickup:
+		return p.fitsInBag(st, i)
+	case StopDrop:
+		return st.visited&(1<<uint(i-1)) != 0
+	case StopWaypoint:
+		return true
+	default:
+		return false
+	}
+}
+
+// finish - Rides on to the end location after the last stop, nothing to do on an open path
+func (p *RouteProblem) finish(st routeState) routeState {
+	if p.end < 0 {
+		return st
+	}
+	next, _ := p.advance(st, p.end)
+	return next
+}
+
+// advance - Moves the rider to stop i and returns the new state with the leg taken
+func (p *RouteProblem) advance(st routeState, i int) (routeState, RouteStep) {
+	stop := p.stops[i]
+	travelTime := p.travelTime(st, i+1)
+	var waitTime float64
+	if stop.Type == StopPickup {
+		waitTime = math.Max(0, stop.ReadyAt-(st.elapsed+travelTime))
+	} else {
+		waitTime = math.Max(0, stop.WindowOpen-(st.elapsed+travelTime))
+	}
+	timeTaken := travelTime + waitTime
 
-	return bestRoute
+	next := routeState{
+		at:           i + 1,
+		elapsed:      st.elapsed + timeTaken,
+		visited:      st.visited | (1 << uint(i)),
+		load:         st.load,
+		weightKg:     st.weightKg,
+		bagDeadlines: st.bagDeadlines,
+	}
+	next.cost = p.addCost(st, i, next.elapsed)
+	switch stop.Type {
+	case StopPickup:
+		next.load += stop.Size
+		next.weightKg += stop.WeightKg
+		if !math.IsInf(stop.MaxInBag, 1) {
+			next.bagDeadlines = append([]float64(nil), st.bagDeadlines...)
+			next.bagDeadlines[stop.OrderIdx] = next.elapsed + stop.MaxInBag
+		}
+	case StopDrop:
+		next.load -= stop.Size
+		next.weightKg -= stop.WeightKg
+	}
+	return next, RouteStep{
+		Step:       stop.Location.Name,
+		StopType:   stop.Type,
+		LocationID: stop.Location.ID,
+		OrderID:    stop.OrderID,
+		TravelTime: travelTime,
+		WaitTime:   waitTime,
+		TimeTaken:  timeTaken,
+		ETA:        next.elapsed,
+		Location:   stop.Location,
+	}
 }
 
-func getAllLocationSequences(orders []models.Order) [][]int64 {
-	r1 := orders[0].ResLocationID
-	r2 := orders[1].ResLocationID
-	c1 := orders[0].CusLocationID
-	c2 := orders[1].CusLocationID
+// travelTime - Minutes to ride from the current point to point `to`, leaving now
+func (p *RouteProblem) travelTime(st routeState, to int) float64 {
+	leg := p.travel[st.at][to]
+	if p.speed == nil {
+		return leg.Minutes
+	}
+	departAt := p.now.Add(time.Duration(st.elapsed * float64(time.Minute)))
+	return p.speed.TravelMinutes(leg.Km, departAt)
+}
 
-	return [][]int64{
-		{r1, r2, c1, c2},
-		{r1, r2, c2, c1},
-		{r2, r1, c1, c2},
-		{r2, r1, c2, c1},
-		{r1, c1, r2, c2},
-		{r2, c2, r1, c1},
+// evaluate - Replays a full visiting sequence and the ride to the end,
+// reporting false if it breaks a constraint
+func (p *RouteProblem) evaluate(seq []int) (routeState, bool) {
+	st, ok := p.evaluatePrefix(seq)
+	if !ok || len(seq) != p.pending {
+		return st, false
 	}
+	return p.finish(st), true
 }
 
-// Returns approax time taken to reach from -> to location
-func getTravelTimeInMinutes(from, to models.Location) float64 {
-	const speed = 20.0 // Speed is in km/h
-	dist := haversine(from.Latitude, from.Longitude, to.Latitude, to.Longitude)
-	return (dist / speed) * 60
+// evaluatePrefix - Same as evaluate but seq may leave stops unvisited and the end is not reached
+func (p *RouteProblem) evaluatePrefix(seq []int) (routeState, bool) {
+	st := p.initialState()
+	for _, i := range seq {
+		var ok bool
+		if st, ok = p.step(st, i); !ok {
+			return st, false
+		}
+	}
+	return st, true
 }
 
-// Users haversine formula to get approax distance between from -> to lat and lon
-func haversine(lat1, lon1, lat2, lon2 float64) float64 {
-	const earthRadiusInKM = 6371
-	dLat := (lat2 - lat1) * (3.14159 / 180)
-	dLon := (lon2 - lon1) * (3.14159 / 180)
-	lat1 *= (3.14159 / 180)
-	lat2 *= (3.14159 / 180)
+// buildResponse - Replays a full visiting sequence into the API response
+func (p *RouteProblem) buildResponse(seq []int) BestRouteResponse {
+	st := p.initialState()
+	route := make([]RouteStep, 0, len(seq)+1)
+	breaches := make([]SLABreach, 0)
+	for _, i := range seq {
+		var step RouteStep
+		st, step = p.advance(st, i)
+		route = append(route, step)
 
-	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
-		math.Sin(dLon/2)*math.Sin(dLon/2)*math.Cos(lat1)*math.Cos(lat2)
-	
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
-	
-	return earthRadiusInKM * c
-}
\ No newline at end of file
+		stop := p.stops[i]
+		if stop.Type != StopDrop {
+			continue
+		}
+		// A missed soft window counts as a missed deadline, the earlier one is reported
+		dueAt, promisedBy := stop.DueAt, stop.PromisedBy
+		if stop.SoftClose < dueAt {
+			dueAt, promisedBy = stop.SoftClose, stop.DeliverBefore
+		}
+		if st.elapsed > dueAt+costEpsilon {
+			breaches = append(breaches, SLABreach{
+				OrderID:       stop.OrderID,
+				PromisedBy:    promisedBy,
+				ETA:           p.minutesFromNow(st.elapsed),
+				LateByMinutes: st.elapsed - dueAt,
+			})
+		}
+	}
+	if p.end >= 0 {
+		var step RouteStep
+		st, step = p.advance(st, p.end)
+		route = append(route, step)
+	}
+
+	return BestRouteResponse{
+		Start:          p.start,
+		TotalTime:      st.elapsed,
+		Objective:      p.objective,
+		ObjectiveValue: st.cost,
+		Route:          route,
+		SLABreaches:    breaches,
+	}
+}
+
+// minutesFromNow - Wall clock time the given minutes after the route starts
+func (p *RouteProblem) minutesFromNow(minutes float64) time.Time {
+	return p.now.Add(time.Duration(minutes * float64(time.Minute)))
+}
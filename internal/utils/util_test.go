@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/SHIVAMSINGH0101/go-demo/internal/models"
+)
+
+// Food that isn't ready yet holds the rider at the restaurant, the wait is part of the pickup step
+func TestPickupWaitsForPrepTime(t *testing.T) {
+	start, orders, locations := lineOrders(5)
+	orders[0].CreatedAt = testNow.Add(-4 * time.Minute)
+	orders[0].PrepTimeInMinutes = 10
+
+	plan, err := PlanRoute(start, orders, locations, RouteOptions{Now: testNow, Solver: exactSolver{}})
+	if err != nil {
+		t.Fatalf("PlanRoute error = %v", err)
+	}
+	route := plan.Response.Route
+	if len(route) != 2 || route[0].StopType != StopPickup || route[1].StopType != StopDrop {
+		t.Fatalf("route = %+v, want a pickup then a drop", route)
+	}
+
+	pickup, drop := route[0], route[1]
+	if pickup.TravelTime != 0 || math.Abs(pickup.WaitTime-6) > 1e-9 || math.Abs(pickup.ETA-6) > 1e-9 {
+		t.Errorf("pickup = %+v, want 6 minutes waiting at the start", pickup)
+	}
+	if drop.WaitTime != 0 || math.Abs(drop.ETA-(6+drop.TravelTime)) > 1e-9 {
+		t.Errorf("drop = %+v, want it reached right after the wait", drop)
+	}
+	if math.Abs(plan.Response.TotalTime-drop.ETA) > 1e-9 {
+		t.Errorf("total time = %.2f, want the drop's ETA %.2f", plan.Response.TotalTime, drop.ETA)
+	}
+}
+
+// Food ready before the rider arrives costs no wait
+func TestPickupOfReadyFoodHasNoWait(t *testing.T) {
+	start, orders, locations := lineOrders(5)
+	orders[0].CreatedAt = testNow.Add(-30 * time.Minute)
+	orders[0].PrepTimeInMinutes = 10
+
+	plan, err := PlanRoute(start, orders, locations, RouteOptions{Now: testNow, Solver: exactSolver{}})
+	if err != nil {
+		t.Fatalf("PlanRoute error = %v", err)
+	}
+	for _, step := range plan.Response.Route {
+		if step.WaitTime != 0 {
+			t.Errorf("step %+v waits, want no wait", step)
+		}
+	}
+}
+
+// A round trip ends back at the start, after every drop
+func TestRouteEndsAtEndLocation(t *testing.T) {
+	start, orders, locations := lineOrders(5, -3)
+	open, err := PlanRoute(start, orders, locations, RouteOptions{Now: testNow, Solver: exactSolver{}})
+	if err != nil {
+		t.Fatalf("PlanRoute error = %v", err)
+	}
+
+	end := start
+	plan, err := PlanRoute(start, orders, locations, RouteOptions{Now: testNow, End: &end, Solver: exactSolver{}})
+	if err != nil {
+		t.Fatalf("PlanRoute with an end error = %v", err)
+	}
+	route := plan.Response.Route
+	last := route[len(route)-1]
+	if last.StopType != StopEnd || last.OrderID != 0 || last.Location != end {
+		t.Fatalf("last step = %+v, want the end location", last)
+	}
+	// Coming back adds to the open path
+	if plan.Response.TotalTime <= open.Response.TotalTime {
+		t.Errorf("round trip takes %.2f minutes, the open path %.2f", plan.Response.TotalTime, open.Response.TotalTime)
+	}
+	if math.Abs(last.ETA-plan.Response.TotalTime) > 1e-9 {
+		t.Errorf("end reached at %.2f, route takes %.2f", last.ETA, plan.Response.TotalTime)
+	}
+}
+
+// A via stop on the way to the customer is passed without a detour
+func TestRoutePassesViaStops(t *testing.T) {
+	start, orders, locations := lineOrders(5)
+	direct, err := PlanRoute(start, orders, locations, RouteOptions{Now: testNow, Solver: exactSolver{}})
+	if err != nil {
+		t.Fatalf("PlanRoute error = %v", err)
+	}
+
+	via := models.Location{ID: 50, Name: "via", Latitude: 12.9 + 2/111.2, Longitude: 77.6}
+	plan, err := PlanRoute(start, orders, locations, RouteOptions{Now: testNow, Waypoints: []models.Location{via}, Solver: exactSolver{}})
+	if err != nil {
+		t.Fatalf("PlanRoute with a via stop error = %v", err)
+	}
+	route := plan.Response.Route
+	if len(route) != 3 || route[1].StopType != StopWaypoint || route[1].LocationID != via.ID {
+		t.Fatalf("route = %+v, want the via stop between pickup and drop", route)
+	}
+	if math.Abs(plan.Response.TotalTime-direct.Response.TotalTime) > 1e-6 {
+		t.Errorf("route through the via stop takes %.6f minutes, direct %.6f", plan.Response.TotalTime, direct.Response.TotalTime)
+	}
+}